@@ -0,0 +1,151 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder composes a simple SELECT query with named params to be run
+// with Query and friends. Columns, table and order by items which are not
+// plain identifiers are quoted, conditions are taken as written.
+type SelectBuilder struct {
+	columns []string
+	from    string
+	where   []string
+	orderBy []string
+	limit   int
+	params  Params
+}
+
+// Select starts a SELECT of given columns, no columns selects *
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns, limit: -1}
+}
+
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.from = table
+	return b
+}
+
+// Where adds a condition joined with AND to the others, params are merged
+// into the query params, where later values replace earlier ones
+func (b *SelectBuilder) Where(cond string, params Params) *SelectBuilder {
+	b.where = append(b.where, cond)
+	b.params = mergeParams(b.params, params)
+	return b
+}
+
+// OrderBy adds ordering, column may be followed by ASC or DESC
+func (b *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, columns...)
+	return b
+}
+
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+// Build returns query text and params
+func (b *SelectBuilder) Build() (string, Params) {
+	var q strings.Builder
+	q.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		q.WriteString("*")
+	}
+	for i, col := range b.columns {
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString(safeIdentifier(col))
+	}
+	if b.from != "" {
+		q.WriteString(" FROM " + safeIdentifier(b.from))
+	}
+	if len(b.where) > 0 {
+		q.WriteString(" WHERE " + andConditions(b.where))
+	}
+	for i, item := range b.orderBy {
+		if i == 0 {
+			q.WriteString(" ORDER BY ")
+		} else {
+			q.WriteString(", ")
+		}
+		q.WriteString(orderByItem(item))
+	}
+	if b.limit >= 0 {
+		q.WriteString(" LIMIT " + strconv.Itoa(b.limit))
+	}
+	params := b.params
+	if params == nil {
+		params = Params{}
+	}
+	return q.String(), params
+}
+
+func orderByItem(item string) string {
+	fields := strings.Fields(item)
+	if len(fields) == 2 {
+		switch dir := strings.ToUpper(fields[1]); dir {
+		case "ASC", "DESC":
+			return safeIdentifier(fields[0]) + " " + dir
+		}
+	}
+	return safeIdentifier(item)
+}
+
+// Conditions collects optional filters into a WHERE condition, e.g.
+//
+//	var c db.Conditions
+//	c.AddIf(name != "", "name = :name", db.Params{"name": name})
+//	c.AddIf(minAge > 0, "age >= :min_age", db.Params{"min_age": minAge})
+//	where, params := c.Build()
+type Conditions struct {
+	clauses []string
+	params  Params
+}
+
+// Add adds clause joined with AND to the others, params are merged
+// into the condition params, where later values replace earlier ones
+func (c *Conditions) Add(clause string, params Params) {
+	c.clauses = append(c.clauses, clause)
+	c.params = mergeParams(c.params, params)
+}
+
+// AddIf adds clause only when cond is true
+func (c *Conditions) AddIf(cond bool, clause string, params Params) {
+	if cond {
+		c.Add(clause, params)
+	}
+}
+
+// Build returns added clauses joined with AND, TRUE when none were added
+func (c *Conditions) Build() (string, Params) {
+	params := c.params
+	if params == nil {
+		params = Params{}
+	}
+	if len(c.clauses) == 0 {
+		return "TRUE", params
+	}
+	return andConditions(c.clauses), params
+}
+
+// andConditions joins conditions with AND, each one is parenthesized
+// when there are several of them
+func andConditions(conds []string) string {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return "(" + strings.Join(conds, ") AND (") + ")"
+}
+
+func mergeParams(dst, src Params) Params {
+	if dst == nil {
+		dst = make(Params, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
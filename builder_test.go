@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	q, params := Select("id", "u.name", "display name").
+		From("public.users").
+		Where("id > :min_id", Params{"min_id": 10}).
+		Where("status = :status OR status IS NULL", Params{"status": "active"}).
+		OrderBy("u.name DESC", "id").
+		Limit(20).
+		Build()
+
+	assert.Equal(t, `SELECT id, u.name, "display name" FROM public.users WHERE (id > :min_id) AND (status = :status OR status IS NULL) ORDER BY u.name DESC, id LIMIT 20`, q)
+	assert.Equal(t, Params{"min_id": 10, "status": "active"}, params)
+
+	rendered, err := qprintf(q, params)
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT id, u.name, "display name" FROM public.users WHERE (id > 10) AND (status = 'active' OR status IS NULL) ORDER BY u.name DESC, id LIMIT 20`, rendered)
+}
+
+func TestSelectBuilderMinimal(t *testing.T) {
+	q, params := Select().From(`weird"table`).Where("id = :id", Params{"id": 1}).Build()
+	assert.Equal(t, `SELECT * FROM "weird""table" WHERE id = :id`, q)
+	assert.Equal(t, Params{"id": 1}, params)
+
+	q, params = Select("t.*").From("t").OrderBy("id; DROP TABLE t").Build()
+	assert.Equal(t, `SELECT t.* FROM t ORDER BY "id; DROP TABLE t"`, q)
+	assert.Equal(t, Params{}, params)
+}
+
+func TestConditions(t *testing.T) {
+	name, minAge, status := "john", 0, "active"
+
+	var c Conditions
+	c.AddIf(name != "", "name = :name", Params{"name": name})
+	c.AddIf(minAge > 0, "age >= :min_age", Params{"min_age": minAge})
+	c.AddIf(status != "", "status = :status OR status IS NULL", Params{"status": status})
+	where, params := c.Build()
+	assert.Equal(t, "(name = :name) AND (status = :status OR status IS NULL)", where)
+	assert.Equal(t, Params{"name": "john", "status": "active"}, params)
+
+	var empty Conditions
+	empty.AddIf(false, "name = :name", Params{"name": name})
+	where, params = empty.Build()
+	assert.Equal(t, "TRUE", where)
+	assert.Equal(t, Params{}, params)
+}
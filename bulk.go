@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BulkInsert inserts rows into table with a single multi-row INSERT and
+// returns the number of rows inserted. Columns are taken from fields of T
+// mapped the same way as for scanning, zero value of a field tagged with
+// "default" option, e.g. `sql:"created_at,default"`, is inserted as DEFAULT
+// to let the column default apply. Empty rows is a no-op.
+func BulkInsert[T any](ctx context.Context, db Queryable, table string, rows []T) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if err := checkQualifiedIdentifier(table); err != nil {
+		return 0, err
+	}
+	typ := reflect.TypeOf(rows).Elem()
+	if typ.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("rows must be a slice of structs; got %T", rows)
+	}
+	columns := getStructInfo(typ).columns
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("%s has no fields mapped to columns", typ)
+	}
+
+	var q strings.Builder
+	q.WriteString("INSERT INTO " + table + " (")
+	for i, col := range columns {
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString(col.name)
+	}
+	q.WriteString(") VALUES ")
+
+	params := make(Params, len(rows)*len(columns))
+	for i := range rows {
+		row := reflect.ValueOf(rows[i])
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteByte('(')
+		for j, col := range columns {
+			if j > 0 {
+				q.WriteString(", ")
+			}
+			field := row.FieldByIndex(col.index)
+			if _, ok := col.options["default"]; ok && field.IsZero() {
+				q.WriteString("DEFAULT")
+				continue
+			}
+			name := "r" + strconv.Itoa(i) + "_" + strconv.Itoa(j)
+			params[name] = field.Interface()
+			q.WriteString(placeholder(name))
+		}
+		q.WriteByte(')')
+	}
+
+	res, err := Exec(ctx, db, q.String(), params)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ReloadTable replaces all rows of table with rows in a single transaction:
+// the table is truncated and rows are inserted with BulkInsert
+func ReloadTable[T any](ctx context.Context, db *sql.DB, table string, rows []T) error {
+	if err := checkQualifiedIdentifier(table); err != nil {
+		return err
+	}
+	return WithTx(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := Exec(ctx, tx, "TRUNCATE "+table, nil); err != nil {
+			return err
+		}
+		_, err := BulkInsert(ctx, tx, table, rows)
+		return err
+	})
+}
+
+// ExecBatch runs statements one by one and returns the total number of
+// affected rows. It stops at the first failed statement, so run it within
+// WithTx to apply the batch atomically. Drivers which can't report affected
+// rows make it fail with the error returned by RowsAffected.
+func ExecBatch(ctx context.Context, db Queryable, batch []QuerySpec) (int64, error) {
+	var total int64
+	for _, spec := range batch {
+		res, err := Exec(ctx, db, spec.SQL, spec.Params)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, wrapError(err, spec.SQL, spec.Params)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// CopyFrom loads rows into table with COPY FROM STDIN the way lib/pq
+// supports it: the COPY statement is prepared, every row is sent with Exec
+// and the final Exec without arguments completes the copy. The number of
+// rows copied is taken from the result of that final Exec. lib/pq requires
+// db to be a transaction.
+func CopyFrom(ctx context.Context, db Queryable, table string, columns []string, rows [][]interface{}) (int64, error) {
+	if err := checkQualifiedIdentifier(table); err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("no columns given")
+	}
+	for _, col := range columns {
+		if err := checkIdentifier(col); err != nil {
+			return 0, err
+		}
+	}
+	q := "COPY " + table + " (" + strings.Join(columns, ", ") + ") FROM STDIN"
+	logQuery(q, nil)
+	stmt, err := db.PrepareContext(ctx, q)
+	if err != nil {
+		return 0, wrapError(err, q, nil)
+	}
+	defer stmt.Close()
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return 0, fmt.Errorf("row %d has %d values, expected %d", i+1, len(row), len(columns))
+		}
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			return 0, wrapError(err, q, nil)
+		}
+	}
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, wrapError(err, q, nil)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, wrapError(err, q, nil)
+	}
+	return n, nil
+}
+
+// BulkUpdate applies changes to rows of table identified by keyCol values,
+// the keys of changes. When every row changes the same columns a single
+// UPDATE with a CASE per column is run, otherwise every row is updated with
+// its own statement as ExecBatch does. Returns the number of rows updated.
+func BulkUpdate(ctx context.Context, db Queryable, table, keyCol string, changes map[interface{}]Params) (int64, error) {
+	if len(changes) == 0 {
+		return 0, nil
+	}
+	if err := checkQualifiedIdentifier(table); err != nil {
+		return 0, err
+	}
+	if err := checkIdentifier(keyCol); err != nil {
+		return 0, err
+	}
+
+	// keys are ordered by their SQL form so the same changes give the same SQL
+	type row struct {
+		key     interface{}
+		sortKey string
+		columns []string
+	}
+	rows := make([]row, 0, len(changes))
+	uniform := true
+	for key, params := range changes {
+		if len(params) == 0 {
+			return 0, fmt.Errorf("no columns to set for key %v", key)
+		}
+		sortKey, err := toDbValue(key)
+		if err != nil {
+			return 0, err
+		}
+		columns := sortedKeys(params)
+		for _, col := range columns {
+			if err = checkIdentifier(col); err != nil {
+				return 0, err
+			}
+		}
+		if len(rows) > 0 && strings.Join(columns, ",") != strings.Join(rows[0].columns, ",") {
+			uniform = false
+		}
+		rows = append(rows, row{key: key, sortKey: sortKey, columns: columns})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].sortKey < rows[j].sortKey
+	})
+
+	if !uniform {
+		batch := make([]QuerySpec, len(rows))
+		for i, r := range rows {
+			params := Params{"key": r.key}
+			sets := make([]string, len(r.columns))
+			for j, col := range r.columns {
+				name := "v" + strconv.Itoa(j)
+				params[name] = changes[r.key][col]
+				sets[j] = col + " = " + placeholder(name)
+			}
+			batch[i] = QuerySpec{
+				SQL:    "UPDATE " + table + " SET " + strings.Join(sets, ", ") + " WHERE " + keyCol + " = " + placeholder("key"),
+				Params: params,
+			}
+		}
+		return ExecBatch(ctx, db, batch)
+	}
+
+	params := make(Params, len(rows)*(len(rows[0].columns)+1))
+	keys := make([]string, len(rows))
+	for i, r := range rows {
+		keys[i] = placeholder("k" + strconv.Itoa(i))
+		params["k"+strconv.Itoa(i)] = r.key
+	}
+	var q strings.Builder
+	q.WriteString("UPDATE " + table + " SET ")
+	for j, col := range rows[0].columns {
+		if j > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString(col + " = CASE " + keyCol)
+		for i, r := range rows {
+			name := "v" + strconv.Itoa(i) + "_" + strconv.Itoa(j)
+			params[name] = changes[r.key][col]
+			q.WriteString(" WHEN " + keys[i] + " THEN " + placeholder(name))
+		}
+		q.WriteString(" ELSE " + col + " END")
+	}
+	q.WriteString(" WHERE " + keyCol + " IN (" + strings.Join(keys, ", ") + ")")
+
+	res, err := Exec(ctx, db, q.String(), params)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
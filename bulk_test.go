@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bulkTestModel struct {
+	ID       int    `sql:"id"`
+	Name     string `sql:"name"`
+	Internal string `sql:"-"`
+}
+
+func TestBulkInsert(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return &fakeResult{RowsAffected: 2}, nil
+	})
+
+	n, err := BulkInsert(context.Background(), dbh, "public.test", []bulkTestModel{
+		{ID: 1, Name: "a:b"},
+		{ID: 2, Name: "it's", Internal: "skipped"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, []string{"INSERT INTO public.test (id, name) VALUES (1, 'a:b'), (2, 'it''s')"}, s.Log())
+
+	n, err = BulkInsert(context.Background(), dbh, "test", []bulkTestModel{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	assert.Len(t, s.Log(), 1)
+
+	_, err = BulkInsert(context.Background(), dbh, "test; DROP TABLE users", []bulkTestModel{{}})
+	assert.Error(t, err)
+}
+
+func TestReloadTable(t *testing.T) {
+	// the handler keeps the table contents to check what it ends up with
+	table := []string{"(0, 'old')"}
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		switch {
+		case strings.HasPrefix(c.Query, "TRUNCATE"):
+			table = nil
+		case strings.HasPrefix(c.Query, "INSERT"):
+			values := strings.SplitAfter(c.Query[strings.Index(c.Query, "VALUES ")+7:], "), ")
+			for _, v := range values {
+				table = append(table, strings.TrimSuffix(v, ", "))
+			}
+			return &fakeResult{RowsAffected: int64(len(values))}, nil
+		}
+		return nil, nil
+	})
+
+	err := ReloadTable(context.Background(), dbh, "test", []bulkTestModel{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"(1, 'a')", "(2, 'b')"}, table)
+	assert.Equal(t, []string{
+		"BEGIN",
+		"TRUNCATE test",
+		"INSERT INTO test (id, name) VALUES (1, 'a'), (2, 'b')",
+		"COMMIT",
+	}, s.Log())
+}
+
+func TestReloadTableEmpty(t *testing.T) {
+	dbh, s := newFakeDB(t, nil)
+
+	err := ReloadTable(context.Background(), dbh, "test", []bulkTestModel(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "TRUNCATE test", "COMMIT"}, s.Log())
+}
+
+func TestReloadTableRollsBack(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if strings.HasPrefix(c.Query, "INSERT") {
+			return nil, errors.New("duplicate key")
+		}
+		return nil, nil
+	})
+
+	err := ReloadTable(context.Background(), dbh, "test", []bulkTestModel{{ID: 1}})
+	assert.Error(t, err)
+	assert.Equal(t, "ROLLBACK", s.Log()[len(s.Log())-1])
+}
+
+func TestBulkInsertDefault(t *testing.T) {
+	type model struct {
+		ID        int        `sql:"id"`
+		CreatedAt *time.Time `sql:"created_at,default"`
+	}
+	dbh, s := newFakeDB(t, nil)
+
+	createdAt := time.Date(2023, 3, 2, 10, 0, 0, 0, time.UTC)
+	_, err := BulkInsert(context.Background(), dbh, "test", []model{{ID: 1}, {ID: 2, CreatedAt: &createdAt}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"INSERT INTO test (id, created_at) VALUES (1, DEFAULT), (2, '2023-03-02 10:00:00+00')"}, s.Log())
+}
+
+func TestExecBatch(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if strings.HasPrefix(c.Query, "DELETE") {
+			return &fakeResult{RowsAffected: 3}, nil
+		}
+		return &fakeResult{RowsAffected: 1}, nil
+	})
+
+	n, err := ExecBatch(context.Background(), dbh, []QuerySpec{
+		{SQL: "UPDATE test SET name = :name WHERE id = :id", Params: Params{"id": 1, "name": "a"}},
+		{SQL: "DELETE FROM test WHERE id > :id", Params: Params{"id": 10}},
+		{SQL: "INSERT INTO test VALUES (:id)", Params: Params{"id": 11}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Len(t, s.Log(), 3)
+
+	n, err = ExecBatch(context.Background(), dbh, []QuerySpec{
+		{SQL: "DELETE FROM test WHERE id > :id", Params: Params{"id": 10}},
+		{SQL: "UPDATE test SET name = :name", Params: nil},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestCopyFrom(t *testing.T) {
+	copied := 0
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if len(c.Args) > 0 {
+			copied++
+			return nil, nil
+		}
+		return &fakeResult{RowsAffected: int64(copied)}, nil
+	})
+
+	n, err := CopyFrom(context.Background(), dbh, "public.test", []string{"id", "name"}, [][]interface{}{
+		{1, "a"},
+		{2, "it's"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, []string{
+		"COPY public.test (id, name) FROM STDIN",
+		"COPY public.test (id, name) FROM STDIN",
+		"COPY public.test (id, name) FROM STDIN",
+	}, s.Log())
+
+	_, err = CopyFrom(context.Background(), dbh, "test", []string{"id", "name"}, [][]interface{}{{1}})
+	assert.Error(t, err)
+
+	_, err = CopyFrom(context.Background(), dbh, "test", []string{"id) TO PROGRAM 'rm'; --"}, nil)
+	assert.Error(t, err)
+
+	_, err = CopyFrom(context.Background(), dbh, "test", nil, [][]interface{}{{}})
+	assert.Error(t, err)
+}
+
+func TestCopyFromRowsAffectedError(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return &fakeResult{NoRowsAffected: len(c.Args) == 0}, nil
+	})
+
+	_, err := CopyFrom(context.Background(), dbh, "test", []string{"id"}, [][]interface{}{{1}})
+	assert.Error(t, err)
+}
+
+func TestBulkUpdate(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return &fakeResult{RowsAffected: int64(strings.Count(c.Query, "WHEN") + 1)}, nil
+	})
+
+	n, err := BulkUpdate(context.Background(), dbh, "test", "id", map[interface{}]Params{
+		2: {"name": "b", "status": "active"},
+		1: {"name": "it's", "status": nil},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, []string{
+		"UPDATE test SET name = CASE id WHEN 1 THEN 'it''s' WHEN 2 THEN 'b' ELSE name END, " +
+			"status = CASE id WHEN 1 THEN NULL WHEN 2 THEN 'active' ELSE status END WHERE id IN (1, 2)",
+	}, s.Log())
+}
+
+func TestBulkUpdateVaryingColumns(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return &fakeResult{RowsAffected: 1}, nil
+	})
+
+	n, err := BulkUpdate(context.Background(), dbh, "public.test", "code", map[interface{}]Params{
+		"b": {"status": "active"},
+		"a": {"name": "x", "status": "blocked"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, []string{
+		"UPDATE public.test SET name = 'x', status = 'blocked' WHERE code = 'a'",
+		"UPDATE public.test SET status = 'active' WHERE code = 'b'",
+	}, s.Log())
+
+	_, err = BulkUpdate(context.Background(), dbh, "test", "id", map[interface{}]Params{1: {"name = 1; --": "x"}})
+	assert.Error(t, err)
+
+	n, err = BulkUpdate(context.Background(), dbh, "test", "id", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
@@ -3,22 +3,61 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/kisielk/sqlstruct"
 	"github.com/shopspring/decimal"
 )
 
-const DateTimeTzFormat = "2006-01-02 15:04:05.999999999-07"
+const (
+	DateTimeTzFormat = "2006-01-02 15:04:05.999999999-07"
+	DateFormat       = "2006-01-02"
+	TimeFormat       = "15:04:05.999999999"
+)
 
 type Params map[string]interface{}
+
+// WithDefaults returns copy of p with defaults added for keys p doesn't
+// have, so optional placeholders resolve to defaults instead of failing.
+// Defaults are values rendered like any other param, not SQL.
+func (p Params) WithDefaults(defaults Params) Params {
+	merged := make(Params, len(p)+len(defaults))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range p {
+		merged[k] = v
+	}
+	return merged
+}
+
+// CommaListParam renders as comma separated list of values for IN (...),
+// nested CommaListParam elements are parenthesized: {{1, 2}, {3, 4}}
+// renders as (1, 2), (3, 4)
 type CommaListParam []interface{}
 
+// RowValuesParam renders rows as parenthesized tuples for VALUES clause,
+// e.g. {{1, "a"}, {2, "b"}} renders as (1, 'a'), (2, 'b')
+type RowValuesParam [][]interface{}
+
+// DateParam renders time as date literal like '2006-01-02'
+type DateParam time.Time
+
+// TimeParam renders time of day literal like '15:04:05'
+type TimeParam time.Time
+
+// PgArray renders as Postgres array literal like '{1,2,"a b"}', nested
+// PgArray elements make multi-dimensional array
+type PgArray []interface{}
+
 type Error struct {
 	cause  error
 	Query  string
@@ -48,49 +87,179 @@ func wrapError(err error, sql string, params Params) error {
 	}
 }
 
+// CaseInsensitiveParams makes placeholders match params regardless of case,
+// e.g. :UserID matches "userid" key. Params whose keys differ only by case
+// can't be used then and make query fail.
+var CaseInsensitiveParams = false
+
+// StrictParams makes queries fail when params has keys which don't appear
+// in the query as placeholders, which usually is a typo in one of them
+var StrictParams = false
+
+// ZeroTimeAsNull makes zero time.Time render as NULL instead of
+// '0001-01-01 00:00:00+00', both as a param, including DateParam,
+// TimeParam and EpochParam, and as an element of ArrayParam, PgArray or
+// of maps and []interface{} stored as JSON. Time fields of structs stored as JSON are
+// encoded by encoding/json and aren't affected.
+// Nil *time.Time is always NULL.
+var ZeroTimeAsNull = false
+
+// TimeLocation, when set, is the location times are converted to before
+// they're rendered, so the written offset doesn't depend on the location
+// the time came with. DateParam, TimeParam and times inside JSON params
+// are not converted.
+// By default times are rendered as is.
+var TimeLocation *time.Location
+
+// Logger receives debug trace of queries
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+// DebugLogger, when set, gets every query run by Exec, Query, QueryRow and
+// the helpers built on them, PreparedQuery, CopyFrom and ExecNonTx before
+// it's executed. It's the query template, not the rendered SQL: the query
+// is logged with placeholders and number of params, param values are not
+// logged as they may be sensitive.
+var DebugLogger Logger
+
+func logQuery(sql string, params Params) {
+	if DebugLogger != nil {
+		DebugLogger.Debugf("db: executing query with %d params: %s", len(params), sql)
+	}
+}
+
 func qprintf(sql string, params Params) (string, error) {
-	isNotWordChar := func(r rune) bool {
-		return !((r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || r == '_' || (r >= '0' && r <= '9'))
+	params, err := normalizeParams(params)
+	if err != nil {
+		return "", err
+	}
+	// all missing params are collected to be reported at once
+	var missing []string
+	var used map[string]bool
+	if StrictParams {
+		used = make(map[string]bool, len(params))
 	}
+	rendered, err := replacePlaceholders(sql, func(param string) (string, error) {
+		v, ok := params[normalizeParamName(param)]
+		if used != nil {
+			used[normalizeParamName(param)] = true
+		}
+		if !ok {
+			for _, m := range missing {
+				if m == param {
+					return "", nil
+				}
+			}
+			missing = append(missing, param)
+			return "", nil
+		}
+		rendered, err := toDbValue(v)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", param, err)
+		}
+		return rendered, nil
+	})
+	if err == nil && len(missing) == 1 {
+		err = fmt.Errorf("parameter %s is missing", missing[0])
+	} else if err == nil && len(missing) > 1 {
+		err = fmt.Errorf("parameters missing: %s", strings.Join(missing, ", "))
+	}
+	if err == nil && StrictParams && len(used) < len(params) {
+		var unused []string
+		for k := range params {
+			if !used[k] {
+				unused = append(unused, k)
+			}
+		}
+		sort.Strings(unused)
+		err = fmt.Errorf("parameters not used: %s", strings.Join(unused, ", "))
+	}
+	if err == nil && ValidateRenderedSQL {
+		err = validateSQL(rendered)
+	}
+	if err != nil {
+		return "", err
+	}
+	return rendered, nil
+}
+
+// normalizeParams lowercases params keys when CaseInsensitiveParams is set
+func normalizeParams(params Params) (Params, error) {
+	if !CaseInsensitiveParams {
+		return params, nil
+	}
+	lowered := make(Params, len(params))
+	original := make(map[string]string, len(params))
+	for k, v := range params {
+		lk := strings.ToLower(k)
+		if prev, ok := original[lk]; ok {
+			if prev > k {
+				prev, k = k, prev
+			}
+			return nil, fmt.Errorf("parameters %s and %s collide when case is ignored", prev, k)
+		}
+		original[lk] = k
+		lowered[lk] = v
+	}
+	return lowered, nil
+}
+
+func normalizeParamName(param string) string {
+	if CaseInsensitiveParams {
+		return strings.ToLower(param)
+	}
+	return param
+}
+
+// ParamPrefix is the char placeholders start with. Setting it to '@' lets
+// queries use @name placeholders, so :: casts and array slices like a[1:2]
+// can't be mistaken for them.
+var ParamPrefix byte = ':'
+
+// placeholder returns placeholder of param for generated queries
+func placeholder(param string) string {
+	return string(ParamPrefix) + param
+}
+
+// replacePlaceholders substitutes every :name placeholder in sql
+// with the string returned by replace for that name. Placeholders inside
+// string literals, quoted identifiers, comments and dollar-quoted strings
+// are not replaced.
+func replacePlaceholders(sql string, replace func(param string) (string, error)) (string, error) {
 	var result strings.Builder
-	s := sql
-	for {
-		idx := strings.IndexRune(s, ':')
-		// ':' not found or its at the end of the string
-		if idx == -1 || idx == len(s)-1 {
-			result.WriteString(s)
-			break
-		}
-		// followed by a non-word char?
-		if isNotWordChar(rune(s[idx+1])) {
-			result.WriteString(s[:idx+2])
-			s = s[idx+2:]
+	written := 0
+	for i := 0; i < len(sql); i++ {
+		if end := quotedEnd(sql, i); end != i {
+			// unterminated one takes the rest of sql
+			if end == -1 {
+				break
+			}
+			i = end - 1
 			continue
 		}
-		result.WriteString(s[:idx])
-		s = s[idx+1:] // skip ':' char
-		// find next \W character
-		idxEnd := strings.IndexFunc(s, isNotWordChar)
-		var param string
-		if idxEnd == -1 {
-			param = s
-		} else {
-			param = s[:idxEnd]
+		if sql[i] != ParamPrefix || i+1 == len(sql) {
+			continue
 		}
-		v, ok := params[param]
-		if !ok {
-			return "", fmt.Errorf("parameter %s is missing", param)
+		// followed by a non-word char, e.g. :: cast
+		if !isWordChar(sql[i+1]) {
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(sql) && isWordChar(sql[end]) {
+			end++
 		}
-		castedValue, err := toDbValue(v)
+		replacement, err := replace(sql[i+1 : end])
 		if err != nil {
 			return "", err
 		}
-		result.WriteString(castedValue)
-		if idxEnd == -1 {
-			break
-		}
-		s = s[idxEnd:]
+		result.WriteString(sql[written:i])
+		result.WriteString(replacement)
+		written = end
+		i = end - 1
 	}
+	result.WriteString(sql[written:])
 	return result.String(), nil
 }
 
@@ -99,6 +268,7 @@ func Exec(ctx context.Context, db Queryable, sql string, params Params) (sql.Res
 	if err != nil {
 		return nil, wrapError(err, sql, params)
 	}
+	logQuery(sql, params)
 	res, err := db.ExecContext(ctx, query)
 	if err != nil {
 		return nil, wrapError(err, sql, params)
@@ -111,6 +281,7 @@ func Query(ctx context.Context, db Queryable, sql string, params Params) (*sql.R
 	if err != nil {
 		return nil, wrapError(err, sql, params)
 	}
+	logQuery(sql, params)
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, wrapError(err, sql, params)
@@ -123,6 +294,7 @@ func QueryRow(ctx context.Context, db Queryable, sql string, params Params) (*sq
 	if err != nil {
 		return nil, wrapError(err, sql, params)
 	}
+	logQuery(sql, params)
 	return db.QueryRowContext(ctx, query), nil
 }
 
@@ -140,6 +312,35 @@ func QueryRowAndScan(ctx context.Context, db Queryable, q string, params Params,
 	return nil
 }
 
+// QueryExists reports whether q returns any rows, q is wrapped into SELECT EXISTS(...)
+func QueryExists(ctx context.Context, db Queryable, q string, params Params) (bool, error) {
+	var exists bool
+	err := QueryRowAndScan(ctx, db, "SELECT EXISTS("+trimStatement(q)+")", params, &exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return exists, err
+}
+
+// trimStatement strips whitespace and a trailing semicolon
+// from q so it can be wrapped or extended
+func trimStatement(q string) string {
+	q = strings.TrimSpace(q)
+	return strings.TrimSpace(strings.TrimSuffix(q, ";"))
+}
+
+// QueryCountEquals runs count query q and fails when the count is not expected
+func QueryCountEquals(ctx context.Context, db Queryable, q string, params Params, expected int) error {
+	var count int
+	if err := QueryRowAndScan(ctx, db, q, params, &count); err != nil {
+		return err
+	}
+	if count != expected {
+		return wrapError(fmt.Errorf("expected count %d, got %d", expected, count), q, params)
+	}
+	return nil
+}
+
 func QueryJSONRowIntoStruct(ctx context.Context, db Queryable, q string, params Params, target interface{}) error {
 	row, err := QueryRow(ctx, db, q, params)
 	if err != nil {
@@ -158,7 +359,7 @@ func QueryJSONRowIntoStruct(ctx context.Context, db Queryable, q string, params
 	return nil
 }
 
-func QueryRowIntoStruct(ctx context.Context, db Queryable, q string, params Params, target interface{}) error {
+func QueryRowIntoStruct(ctx context.Context, db Queryable, q string, params Params, target interface{}, opts ...ScanOption) error {
 	rows, err := Query(ctx, db, q, params)
 	if err != nil {
 		return err
@@ -167,23 +368,24 @@ func QueryRowIntoStruct(ctx context.Context, db Queryable, q string, params Para
 	if !rows.Next() {
 		return sql.ErrNoRows
 	}
-	if err = sqlstruct.Scan(target, rows); err != nil {
+	if err = scanStruct(rows, target, newScanConfig(opts)); err != nil {
 		return wrapError(err, q, params)
 	}
 	return nil
 }
 
-func QueryRowsIntoSlice(ctx context.Context, db Queryable, q string, params Params, target interface{}) (interface{}, error) {
+func QueryRowsIntoSlice(ctx context.Context, db Queryable, q string, params Params, target interface{}, opts ...ScanOption) (interface{}, error) {
 	rows, err := Query(ctx, db, q, params)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	cfg := newScanConfig(opts)
 	elemType := reflect.TypeOf(target)
 	v := reflect.MakeSlice(reflect.SliceOf(elemType), 0, 0)
 	for rows.Next() {
 		elemPtr := reflect.New(elemType)
-		if err := sqlstruct.Scan(elemPtr.Interface(), rows); err != nil {
+		if err := scanStruct(rows, elemPtr.Interface(), cfg); err != nil {
 			return nil, wrapError(err, q, params)
 		}
 		elem := reflect.Indirect(elemPtr)
@@ -192,6 +394,53 @@ func QueryRowsIntoSlice(ctx context.Context, db Queryable, q string, params Para
 	return v.Interface(), nil
 }
 
+// QueryColumns appends values of every result column to the slice
+// pointed to by the dest of the same position, e.g.
+//
+//	var ids []int64
+//	var names []string
+//	err := db.QueryColumns(ctx, dbh, "SELECT id, name FROM t", nil, &ids, &names)
+func QueryColumns(ctx context.Context, db Queryable, q string, params Params, dests ...interface{}) error {
+	slices := make([]reflect.Value, len(dests))
+	for i, dest := range dests {
+		v := reflect.ValueOf(dest)
+		if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+			return wrapError(fmt.Errorf("dest %d must be pointer to slice; got %T", i+1, dest), q, params)
+		}
+		slices[i] = v.Elem()
+	}
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return wrapError(err, q, params)
+	}
+	if len(cols) != len(dests) {
+		return wrapError(fmt.Errorf("query returns %d columns, %d destinations given", len(cols), len(dests)), q, params)
+	}
+	elems := make([]reflect.Value, len(dests))
+	values := make([]interface{}, len(dests))
+	for rows.Next() {
+		for i, s := range slices {
+			elems[i] = reflect.New(s.Type().Elem())
+			values[i] = elems[i].Interface()
+		}
+		if err = rows.Scan(values...); err != nil {
+			return wrapError(err, q, params)
+		}
+		for i, s := range slices {
+			s.Set(reflect.Append(s, elems[i].Elem()))
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return wrapError(err, q, params)
+	}
+	return nil
+}
+
 func ScanJSONRowsIntoStruct(rows *sql.Rows, target interface{}) error {
 	var data []byte
 	if err := rows.Scan(&data); err != nil {
@@ -206,14 +455,19 @@ func toDbValue(value interface{}) (string, error) {
 	if value == nil {
 		return "NULL", nil
 	}
+	if r, ok := registeredRenderer(value); ok {
+		return r(value)
+	}
 	switch value := value.(type) {
+	case paramRenderer:
+		return value.renderParam()
 	case *string:
 		if value == nil {
 			return "NULL", nil
 		}
 		return toDbValue(*value)
 	case string:
-		return quoteLiteral(value), nil
+		return QuoteLiteral(value), nil
 	case *int:
 		if value == nil {
 			return "NULL", nil
@@ -221,13 +475,83 @@ func toDbValue(value interface{}) (string, error) {
 		return toDbValue(*value)
 	case int:
 		return strconv.Itoa(value), nil
+	case *int8:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case int8:
+		return strconv.FormatInt(int64(value), 10), nil
+	case *int16:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case int16:
+		return strconv.FormatInt(int64(value), 10), nil
+	case *int32:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case int32:
+		return strconv.FormatInt(int64(value), 10), nil
+	case *int64:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case *uint:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case uint:
+		return strconv.FormatUint(uint64(value), 10), nil
+	case *uint8:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case uint8:
+		return strconv.FormatUint(uint64(value), 10), nil
+	case *uint16:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case uint16:
+		return strconv.FormatUint(uint64(value), 10), nil
+	case *uint32:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case uint32:
+		return strconv.FormatUint(uint64(value), 10), nil
+	case *uint64:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case uint64:
+		return strconv.FormatUint(value, 10), nil
 	case *float64:
 		if value == nil {
 			return "NULL", nil
 		}
 		return toDbValue(*value)
 	case float64:
-		return strconv.FormatFloat(value, 'g', -1, 64), nil
+		return floatLiteral(value, 64), nil
+	case *float32:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case float32:
+		return floatLiteral(float64(value), 32), nil
 	case *bool:
 		if value == nil {
 			return "NULL", nil
@@ -248,7 +572,76 @@ func toDbValue(value interface{}) (string, error) {
 		}
 		return toDbValue(*value)
 	case time.Time:
-		return quoteLiteral(value.Format(DateTimeTzFormat)), nil
+		return formatTimeParam(value, DateTimeTzFormat), nil
+	case *DateParam:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case DateParam:
+		return formatTimeParam(time.Time(value), DateFormat), nil
+	case *TimeParam:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case TimeParam:
+		return formatTimeParam(time.Time(value), TimeFormat), nil
+	case *time.Duration:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
+	case time.Duration:
+		// seconds are exact, Postgres rounds them to microseconds
+		return "'" + decimal.New(int64(value), -9).String() + " secs'::interval", nil
+	case json.RawMessage:
+		if len(value) == 0 {
+			return "NULL", nil
+		}
+		if !json.Valid(value) {
+			return "", errors.New("json.RawMessage param is not valid JSON")
+		}
+		return QuoteLiteral(string(value)), nil
+	case []byte:
+		return byteaLiteral(value), nil
+	case PgArray:
+		if value == nil {
+			return "NULL", nil
+		}
+		var b strings.Builder
+		if err := writeArrayLiteral(&b, value); err != nil {
+			return "", err
+		}
+		return QuoteLiteral(b.String()), nil
+	case RowValuesParam:
+		if value == nil {
+			return "NULL", nil
+		}
+		if len(value) == 0 {
+			return "", errors.New("RowValuesParam has no rows")
+		}
+		rows := make([]string, len(value))
+		for i, row := range value {
+			if len(row) == 0 {
+				return "", fmt.Errorf("RowValuesParam row %d is empty", i+1)
+			}
+			e := make([]string, len(row))
+			for j := range row {
+				var err error
+				e[j], err = toDbValue(row[j])
+				if err != nil {
+					return "", err
+				}
+			}
+			rows[i] = "(" + strings.Join(e, ", ") + ")"
+		}
+		return strings.Join(rows, ", "), nil
+	case *CommaListParam:
+		if value == nil {
+			return "NULL", nil
+		}
+		return toDbValue(*value)
 	case CommaListParam:
 		e := make([]string, len(value))
 		for i := range value {
@@ -257,11 +650,48 @@ func toDbValue(value interface{}) (string, error) {
 			if err != nil {
 				return "", err
 			}
+			// nested lists are grouped, e.g. for VALUES or tuple IN lists
+			switch inner := value[i].(type) {
+			case CommaListParam:
+				e[i] = "(" + e[i] + ")"
+			case *CommaListParam:
+				if inner != nil {
+					e[i] = "(" + e[i] + ")"
+				}
+			}
 		}
 		return strings.Join(e, ", "), nil
 	}
+	// custom types and sql.Null* wrappers are rendered as their driver value
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+			return "NULL", nil
+		}
+		dv, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		// bytes of valuers are usually encoded text like JSON, not bytea
+		if b, ok := dv.([]byte); ok {
+			if b == nil {
+				return "NULL", nil
+			}
+			return QuoteLiteral(string(b)), nil
+		}
+		return toDbValue(dv)
+	}
+	if err, ok := value.(error); ok {
+		return "", fmt.Errorf("error value %q passed as parameter, wrap it in ErrorParam to store the message", err.Error())
+	}
+	switch kind := reflect.Indirect(reflect.ValueOf(value)).Kind(); kind {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return "", fmt.Errorf("%s value of type %T can't be passed as parameter", kind, value)
+	}
 	// the value is either slice or map, so insert it as JSON string
-	// fixme: marshaller doesn't know how to encode map[interface{}]interface{}
+	value, err := jsonCompatible(value)
+	if err != nil {
+		return "", err
+	}
 	encoded, err := json.Marshal(value)
 	if err != nil {
 		return "", err
@@ -270,12 +700,159 @@ func toDbValue(value interface{}) (string, error) {
 	if encoded == nil || asString == "null" {
 		return "NULL", nil
 	}
-	return quoteLiteral(asString), nil
+	return QuoteLiteral(asString), nil
+}
+
+// formatTimeParam renders quoted time in layout, zero time is
+// rendered as NULL when ZeroTimeAsNull is set
+func formatTimeParam(t time.Time, layout string) string {
+	if ZeroTimeAsNull && t.IsZero() {
+		return "NULL"
+	}
+	return QuoteLiteral(formatTime(t, layout))
+}
+
+// formatTime formats t in layout, timestamps with time zone are converted
+// to TimeLocation when it's set. Dates and times of day are kept in their
+// own location, as converting would change them.
+func formatTime(t time.Time, layout string) string {
+	if TimeLocation != nil && layout == DateTimeTzFormat {
+		t = t.In(TimeLocation)
+	}
+	return t.Format(layout)
+}
+
+// floatLiteral formats float with the shortest representation for bitSize
+// precision. NaN and infinities are rendered as 'NaN', 'Infinity' and
+// '-Infinity' which Postgres accepts for both floating point and numeric.
+func floatLiteral(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "'NaN'"
+	case math.IsInf(f, 1):
+		return "'Infinity'"
+	case math.IsInf(f, -1):
+		return "'-Infinity'"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// writeArrayLiteral writes array elements in Postgres array input syntax
+func writeArrayLiteral(b *strings.Builder, arr PgArray) error {
+	b.WriteByte('{')
+	for i, elem := range arr {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		switch elem := elem.(type) {
+		case nil:
+			b.WriteString("NULL")
+		case PgArray:
+			if err := writeArrayLiteral(b, elem); err != nil {
+				return err
+			}
+		case string:
+			writeArrayString(b, elem)
+		case time.Time:
+			if ZeroTimeAsNull && elem.IsZero() {
+				b.WriteString("NULL")
+			} else {
+				writeArrayString(b, formatTime(elem, DateTimeTzFormat))
+			}
+		case decimal.Decimal:
+			b.WriteString(elem.String())
+		default:
+			switch reflect.ValueOf(elem).Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+				s, err := toDbValue(elem)
+				if err != nil {
+					return err
+				}
+				// NaN and infinities come quoted
+				b.WriteString(strings.Trim(s, "'"))
+			default:
+				return fmt.Errorf("PgArray: unsupported element type %T", elem)
+			}
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// writeArrayString writes double quoted array element
+func writeArrayString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for _, c := range s {
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('"')
+}
+
+// jsonCompatible recursively converts map[interface{}]interface{}, which is
+// what YAML decoders produce and json.Marshal can't encode, into
+// map[string]interface{}. Keys must be strings or numbers. Zero times are
+// replaced with nil when ZeroTimeAsNull is set.
+func jsonCompatible(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			var key string
+			switch k := k.(type) {
+			case string:
+				key = k
+			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+				key = fmt.Sprint(k)
+			default:
+				return nil, fmt.Errorf("map key %v of type %T can't be encoded as JSON", k, k)
+			}
+			converted, err := jsonCompatible(v)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = converted
+		}
+		return m, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			converted, err := jsonCompatible(v)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = converted
+		}
+		return m, nil
+	case time.Time:
+		if ZeroTimeAsNull && value.IsZero() {
+			return nil, nil
+		}
+		return value, nil
+	case []interface{}:
+		if value == nil {
+			return value, nil
+		}
+		s := make([]interface{}, len(value))
+		for i, v := range value {
+			converted, err := jsonCompatible(v)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = converted
+		}
+		return s, nil
+	}
+	return value, nil
 }
 
-// quoteLiteral properly escapes string to be safely
-// passed as a value in SQL query
-func quoteLiteral(s string) string {
+// QuoteLiteral properly escapes string to be safely
+// passed as a value in SQL query, strings with backslashes are written
+// with E prefix
+func QuoteLiteral(s string) string {
 	var b strings.Builder
 	b.Grow(len(s)*2 + 3)
 
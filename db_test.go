@@ -1,8 +1,16 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -167,3 +175,631 @@ func TestQprintf(t *testing.T) {
 		})
 	}
 }
+
+func TestQprintfCaseInsensitive(t *testing.T) {
+	CaseInsensitiveParams = true
+	defer func() { CaseInsensitiveParams = false }()
+
+	result, err := qprintf("WHERE id = :UserID AND name = :name", Params{"userid": 1, "NAME": "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "WHERE id = 1 AND name = 'a'", result)
+
+	_, err = qprintf("WHERE id = :id", Params{"id": 1, "ID": 2})
+	assert.Error(t, err)
+}
+
+func TestQprintfCaseSensitiveByDefault(t *testing.T) {
+	_, err := qprintf("WHERE id = :UserID", Params{"userid": 1})
+	assert.Error(t, err)
+}
+
+func TestQueryExists(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("exists", []driver.Value{strings.Contains(c.Query, "id = 1")}), nil
+	})
+
+	exists, err := QueryExists(context.Background(), dbh, "SELECT 1 FROM test WHERE id = :id", Params{"id": 1})
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = QueryExists(context.Background(), dbh, "SELECT 1 FROM test WHERE id = :id", Params{"id": 2})
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.Equal(t, []string{
+		"SELECT EXISTS(SELECT 1 FROM test WHERE id = 1)",
+		"SELECT EXISTS(SELECT 1 FROM test WHERE id = 2)",
+	}, s.Log())
+}
+
+func TestTrimStatement(t *testing.T) {
+	var cases = []struct {
+		q        string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"SELECT 1;", "SELECT 1"},
+		{"  SELECT 1 ;\n\t", "SELECT 1"},
+		{"SELECT ';'", "SELECT ';'"},
+		{"SELECT 1;;", "SELECT 1;"},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			assert.Equal(t, c.expected, trimStatement(c.q))
+		})
+	}
+
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("exists", []driver.Value{true}), nil
+	})
+	_, err := QueryExists(context.Background(), dbh, "SELECT 1 FROM test WHERE id = :id;\n", Params{"id": 1})
+	assert.NoError(t, err)
+	_, err = QueryFirst[scanTestModel](context.Background(), dbh, "SELECT id, name FROM test ; ", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"SELECT EXISTS(SELECT 1 FROM test WHERE id = 1)",
+		"SELECT id, name FROM test LIMIT 1",
+	}, s.Log())
+}
+
+func TestQueryExistsNoRows(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("exists"), nil
+	})
+
+	exists, err := QueryExists(context.Background(), dbh, "SELECT 1 FROM test", nil)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestQueryCountEquals(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("count", []driver.Value{int64(3)}), nil
+	})
+
+	err := QueryCountEquals(context.Background(), dbh, "SELECT count(*) FROM test WHERE kind = :kind", Params{"kind": "a"}, 3)
+	assert.NoError(t, err)
+
+	err = QueryCountEquals(context.Background(), dbh, "SELECT count(*) FROM test WHERE kind = :kind", Params{"kind": "a"}, 2)
+	assert.EqualError(t, err, "expected count 2, got 3")
+	var dbErr *Error
+	if assert.ErrorAs(t, err, &dbErr) {
+		assert.Equal(t, "SELECT count(*) FROM test WHERE kind = :kind", dbErr.Query)
+	}
+}
+
+func TestQueryColumns(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name",
+			[]driver.Value{int64(1), "first"},
+			[]driver.Value{int64(2), "second"},
+		), nil
+	})
+
+	var ids []int64
+	var names []string
+	err := QueryColumns(context.Background(), dbh, "SELECT id, name FROM test", nil, &ids, &names)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+	assert.Equal(t, []string{"first", "second"}, names)
+
+	err = QueryColumns(context.Background(), dbh, "SELECT id, name FROM test", nil, &ids)
+	assert.Error(t, err)
+
+	err = QueryColumns(context.Background(), dbh, "SELECT id, name FROM test", nil, ids, &names)
+	assert.Error(t, err)
+}
+
+func TestUnsupportedParamKinds(t *testing.T) {
+	var cases = []struct {
+		value interface{}
+		kind  string
+	}{
+		{func() {}, "func"},
+		{make(chan int), "chan"},
+		{complex(1, 2), "complex128"},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			_, err := qprintf("WHERE a = :callback", Params{"callback": c.value})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "callback")
+				assert.Contains(t, err.Error(), c.kind)
+			}
+		})
+	}
+}
+
+func TestQprintfIntegerWidths(t *testing.T) {
+	var nilInt8 *int8
+	var nilInt64 *int64
+	var nilUint *uint
+	var nilUint64 *uint64
+	i32, u16 := int32(-7), uint16(7)
+
+	var cases = []struct {
+		value    interface{}
+		expected string
+	}{
+		{int8(math.MinInt8), "-128"},
+		{int8(math.MaxInt8), "127"},
+		{int16(math.MinInt16), "-32768"},
+		{int16(math.MaxInt16), "32767"},
+		{int32(math.MinInt32), "-2147483648"},
+		{int32(math.MaxInt32), "2147483647"},
+		{int64(math.MinInt64), "-9223372036854775808"},
+		{int64(math.MaxInt64), "9223372036854775807"},
+		{uint(math.MaxUint32), "4294967295"},
+		{uint8(math.MaxUint8), "255"},
+		{uint16(math.MaxUint16), "65535"},
+		{uint32(math.MaxUint32), "4294967295"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{&i32, "-7"},
+		{&u16, "7"},
+		{nilInt8, "NULL"},
+		{nilInt64, "NULL"},
+		{nilUint, "NULL"},
+		{nilUint64, "NULL"},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			result, err := qprintf(":a", Params{"a": c.value})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, result)
+		})
+	}
+}
+
+func TestQprintfFloat32(t *testing.T) {
+	var nilFloat32 *float32
+	f32 := float32(0.1)
+
+	result, err := qprintf(":a, :b, :c, :d, :e", Params{"a": float32(0.1), "b": &f32, "c": nilFloat32, "d": 0.1, "e": float64(f32)})
+	assert.NoError(t, err)
+	assert.Equal(t, "0.1, 0.1, NULL, 0.1, 0.10000000149011612", result)
+}
+
+type testStatus int
+
+func (s testStatus) Value() (driver.Value, error) {
+	switch s {
+	case 1:
+		return "active", nil
+	case 2:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown status %d", int(s))
+}
+
+type testID struct {
+	id int64
+}
+
+func (id *testID) Value() (driver.Value, error) {
+	return id.id, nil
+}
+
+type testJSONB map[string]interface{}
+
+func (j testJSONB) Value() (driver.Value, error) {
+	if j == nil {
+		return []byte(nil), nil
+	}
+	return json.Marshal(j)
+}
+
+func TestQprintfValuer(t *testing.T) {
+	var nilID *testID
+
+	result, err := qprintf(":a, :b, :c, :d", Params{"a": testStatus(1), "b": testStatus(2), "c": &testID{42}, "d": nilID})
+	assert.NoError(t, err)
+	assert.Equal(t, "'active', NULL, 42, NULL", result)
+
+	result, err = qprintf(":a, :b", Params{"a": testJSONB{"k": "it's"}, "b": testJSONB(nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, `'{"k":"it''s"}', NULL`, result)
+
+	_, err = qprintf(":a", Params{"a": testStatus(3)})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown status 3")
+	}
+}
+
+func TestBytea(t *testing.T) {
+	result, err := qprintf(":a, :b, :c", Params{"a": []byte{0xde, 0xad, 0x00}, "b": []byte{}, "c": []byte(nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, `E'\\xdead00', E'\\x', NULL`, result)
+
+	// the mock decodes bytea literal the way Postgres does and returns it back
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		literal := strings.TrimSuffix(strings.TrimPrefix(c.Query, `SELECT E'\\x`), `'::bytea`)
+		data, err := hex.DecodeString(literal)
+		if err != nil {
+			return nil, err
+		}
+		return rowsOf("data", []driver.Value{data}), nil
+	})
+	sent := []byte("binary\x00\xff'\\data")
+	var received []byte
+	err = QueryRowAndScan(context.Background(), dbh, "SELECT :data::bytea", Params{"data": sent}, &received)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, received)
+}
+
+func TestQprintfSQLNullTypes(t *testing.T) {
+	ts := time.Date(2023, 3, 2, 10, 30, 0, 0, time.UTC)
+
+	var cases = []struct {
+		value    interface{}
+		expected string
+	}{
+		{sql.NullString{String: "it's", Valid: true}, "'it''s'"},
+		{sql.NullString{String: "ignored"}, "NULL"},
+		{sql.NullInt64{Int64: 42, Valid: true}, "42"},
+		{sql.NullInt64{Int64: 42}, "NULL"},
+		{sql.NullInt32{Int32: -7, Valid: true}, "-7"},
+		{sql.NullInt32{}, "NULL"},
+		{sql.NullFloat64{Float64: 0.5, Valid: true}, "0.5"},
+		{sql.NullFloat64{}, "NULL"},
+		{sql.NullBool{Bool: true, Valid: true}, "true"},
+		{sql.NullBool{Bool: true}, "NULL"},
+		{sql.NullTime{Time: ts, Valid: true}, "'2023-03-02 10:30:00+00'"},
+		{sql.NullTime{Time: ts}, "NULL"},
+		{&sql.NullString{String: "x", Valid: true}, "'x'"},
+		{(*sql.NullString)(nil), "NULL"},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			result, err := qprintf(":a", Params{"a": c.value})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, result)
+		})
+	}
+}
+
+func TestQprintfInterfaceMaps(t *testing.T) {
+	value := map[interface{}]interface{}{
+		"name": "config",
+		1:      "one",
+		"nested": map[interface{}]interface{}{
+			"list": []interface{}{map[interface{}]interface{}{"a": true}, 2.5},
+		},
+		"plain": map[string]interface{}{"inner": map[interface{}]interface{}{"b": nil}},
+	}
+	result, err := qprintf(":a", Params{"a": value})
+	assert.NoError(t, err)
+	assert.Equal(t, `'{"1":"one","name":"config","nested":{"list":[{"a":true},2.5]},"plain":{"inner":{"b":null}}}'`, result)
+
+	_, err = qprintf(":a", Params{"a": map[interface{}]interface{}{
+		"nested": map[interface{}]interface{}{[2]int{1, 2}: "x"},
+	}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "[2]int")
+	}
+}
+
+func TestQprintfPgArray(t *testing.T) {
+	var cases = []struct {
+		value    PgArray
+		expected string
+	}{
+		{PgArray{1, 2, int64(3)}, `'{1,2,3}'`},
+		{PgArray{"a", "it's", `say "hi"`, nil}, `E'{"a","it''s","say \\"hi\\"",NULL}'`},
+		{PgArray{`back\slash`}, `E'{"back\\\\slash"}'`},
+		{PgArray{PgArray{1, 2}, PgArray{3, 4}}, `'{{1,2},{3,4}}'`},
+		{PgArray{decimal.RequireFromString("1.50"), 0.25, true}, `'{1.5,0.25,true}'`},
+		{PgArray{math.NaN(), math.Inf(-1)}, `'{NaN,-Infinity}'`},
+		{PgArray{}, `'{}'`},
+		{nil, `NULL`},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			result, err := qprintf(":a", Params{"a": c.value})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, result)
+		})
+	}
+
+	_, err := qprintf(":a", Params{"a": PgArray{testStruct{}}})
+	assert.Error(t, err)
+}
+
+func TestQprintfDuration(t *testing.T) {
+	var nilDuration *time.Duration
+	d := 90 * time.Minute
+
+	var cases = []struct {
+		value    interface{}
+		expected string
+	}{
+		{1500 * time.Microsecond, "'0.0015 secs'::interval"},
+		{time.Nanosecond, "'0.000000001 secs'::interval"},
+		{26*time.Hour + 30*time.Second, "'93630 secs'::interval"},
+		{-2500 * time.Millisecond, "'-2.5 secs'::interval"},
+		{time.Duration(0), "'0 secs'::interval"},
+		{&d, "'5400 secs'::interval"},
+		{nilDuration, "NULL"},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			result, err := qprintf(":a", Params{"a": c.value})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, result)
+		})
+	}
+}
+
+func TestQprintfSpecialFloats(t *testing.T) {
+	result, err := qprintf(":a, :b, :c, :d, :e, :f", Params{
+		"a": math.NaN(),
+		"b": math.Inf(1),
+		"c": math.Inf(-1),
+		"d": float32(math.NaN()),
+		"e": float32(math.Inf(1)),
+		"f": float32(math.Inf(-1)),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "'NaN', 'Infinity', '-Infinity', 'NaN', 'Infinity', '-Infinity'", result)
+}
+
+func TestZeroTimeAsNull(t *testing.T) {
+	var zero time.Time
+	ts := time.Date(2023, 3, 2, 10, 30, 0, 0, time.UTC)
+	params := Params{
+		"a": zero,
+		"b": ts,
+		"c": ArrayParam{Values: []time.Time{ts, zero}, Type: "timestamptz"},
+		"d": PgArray{zero},
+		"e": map[string]interface{}{"at": zero},
+	}
+	q := ":a, :b, :c, :d, :e"
+
+	result, err := qprintf(q, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "'0001-01-01 00:00:00+00', '2023-03-02 10:30:00+00', "+
+		"ARRAY['2023-03-02 10:30:00+00', '0001-01-01 00:00:00+00']::timestamptz[], "+
+		`'{"0001-01-01 00:00:00+00"}', '{"at":"0001-01-01T00:00:00Z"}'`, result)
+
+	ZeroTimeAsNull = true
+	defer func() { ZeroTimeAsNull = false }()
+	result, err = qprintf(q, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "NULL, '2023-03-02 10:30:00+00', ARRAY['2023-03-02 10:30:00+00', NULL]::timestamptz[], '{NULL}', '{\"at\":null}'", result)
+}
+
+func TestQprintfJSONRawMessage(t *testing.T) {
+	result, err := qprintf(":a, :b, :c, :d", Params{
+		"a": json.RawMessage(`{"name": "it's", "tags": [1, 2]}`),
+		"b": map[string]interface{}{"name": "it's", "tags": []int{1, 2}},
+		"c": json.RawMessage(nil),
+		"d": json.RawMessage(`null`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `'{"name": "it''s", "tags": [1, 2]}', '{"name":"it''s","tags":[1,2]}', NULL, 'null'`, result)
+
+	_, err = qprintf(":a", Params{"a": json.RawMessage(`{"broken"`)})
+	assert.Error(t, err)
+}
+
+func TestQprintfDateAndTime(t *testing.T) {
+	ts := time.Date(2023, 3, 2, 18, 30, 5, 250000000, time.UTC)
+	date, tod := DateParam(ts), TimeParam(ts)
+	var nilDate *DateParam
+	var nilTime *TimeParam
+
+	result, err := qprintf(":a, :b, :c, :d, :e, :f", Params{
+		"a": DateParam(ts),
+		"b": TimeParam(ts),
+		"c": &date,
+		"d": &tod,
+		"e": nilDate,
+		"f": nilTime,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "'2023-03-02', '18:30:05.25', '2023-03-02', '18:30:05.25', NULL, NULL", result)
+}
+
+func TestTimeLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	ts := time.Date(2023, 3, 1, 21, 30, 0, 0, newYork)
+	params := Params{"a": ts, "b": PgArray{ts}, "c": DateParam(ts), "d": TimeParam(ts)}
+
+	result, err := qprintf(":a, :b, :c, :d", params)
+	assert.NoError(t, err)
+	assert.Equal(t, `'2023-03-01 21:30:00-05', '{"2023-03-01 21:30:00-05"}', '2023-03-01', '21:30:00'`, result)
+
+	// dates and times of day keep their location
+	TimeLocation = time.UTC
+	defer func() { TimeLocation = nil }()
+	result, err = qprintf(":a, :b, :c, :d", params)
+	assert.NoError(t, err)
+	assert.Equal(t, `'2023-03-02 02:30:00+00', '{"2023-03-02 02:30:00+00"}', '2023-03-01', '21:30:00'`, result)
+}
+
+func TestQprintfSkipsStringLiterals(t *testing.T) {
+	var cases = []struct {
+		SQL            string
+		expectedResult string
+	}{
+		{"SELECT 'time: :00', :a", "SELECT 'time: :00', 1"},
+		{"SELECT 'it''s :a', :a", "SELECT 'it''s :a', 1"},
+		{"SELECT '''' || :a || ':a'''", "SELECT '''' || 1 || ':a'''"},
+		{`SELECT E'it\'s :a', :a`, `SELECT E'it\'s :a', 1`},
+		{`SELECT e'\\', :a`, `SELECT e'\\', 1`},
+		{"SELECT '12:30'::time, :a::text", "SELECT '12:30'::time, 1::text"},
+		{"SELECT ':a", "SELECT ':a"},
+		{"SELECT * -- user's rows\nFROM t WHERE id = :a", "SELECT * -- user's rows\nFROM t WHERE id = 1"},
+		{"SELECT :a -- :b", "SELECT 1 -- :b"},
+		{"SELECT /* it's :b */ :a", "SELECT /* it's :b */ 1"},
+		{`SELECT "it's" FROM t WHERE id = :a`, `SELECT "it's" FROM t WHERE id = 1`},
+		{`SELECT ":b""" FROM t WHERE id = :a`, `SELECT ":b""" FROM t WHERE id = 1`},
+		{"SELECT $$it's :b$$, $f$:b$f$, :a", "SELECT $$it's :b$$, $f$:b$f$, 1"},
+		{"SELECT a$b$c, :a", "SELECT a$b$c, 1"},
+		{`SELECT CASE WHEN TRUE THEN :a ELSE'\' END, :a`, `SELECT CASE WHEN TRUE THEN 1 ELSE'\' END, 1`},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			result, err := qprintf(c.SQL, Params{"a": 1})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedResult, result)
+		})
+	}
+}
+
+func TestParamPrefix(t *testing.T) {
+	ParamPrefix = '@'
+	defer func() { ParamPrefix = ':' }()
+
+	result, err := qprintf("SELECT @foo::int, ids[1:2], :foo, tsv @@ @q, '@foo' FROM t WHERE id = @id", Params{"foo": "1", "q": RawSQL("to_tsquery('a')"), "id": 7})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT '1'::int, ids[1:2], :foo, tsv @@ to_tsquery('a'), '@foo' FROM t WHERE id = 7", result)
+
+	where, err := WhereEq(Params{"id": 7})
+	assert.NoError(t, err)
+	assert.Equal(t, "id = @id", where)
+	result, err = qprintf(where, Params{"id": 7})
+	assert.NoError(t, err)
+	assert.Equal(t, "id = 7", result)
+}
+
+func TestQprintfMissingParams(t *testing.T) {
+	_, err := qprintf("WHERE a = :a AND b = :b AND c = :c OR a > :a AND d = :d", Params{"b": 1})
+	assert.EqualError(t, err, "parameters missing: a, c, d")
+
+	_, err = qprintf("WHERE a = :a AND b = :b", Params{"b": 1})
+	assert.EqualError(t, err, "parameter a is missing")
+
+	_, err = Exec(context.Background(), nil, "WHERE a = :a AND c = :c", nil)
+	var dbErr *Error
+	if assert.ErrorAs(t, err, &dbErr) {
+		assert.Equal(t, "parameters missing: a, c", dbErr.Error())
+	}
+}
+
+func TestStrictParams(t *testing.T) {
+	q := "UPDATE users SET name = :name WHERE id = :usr_id"
+	params := Params{"name": "a", "user_id": 1, "usr_id": 1, "extra": true}
+
+	result, err := qprintf(q, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET name = 'a' WHERE id = 1", result)
+
+	StrictParams = true
+	defer func() { StrictParams = false }()
+	_, err = qprintf(q, params)
+	assert.EqualError(t, err, "parameters not used: extra, user_id")
+
+	_, err = qprintf(q+" AND name <> :name", Params{"name": "a", "usr_id": 1})
+	assert.NoError(t, err)
+}
+
+func TestParamsWithDefaults(t *testing.T) {
+	q := "SELECT * FROM t WHERE status = :status AND kind = :kind LIMIT :limit"
+	defaults := Params{"status": "active", "limit": LimitParam(20), "kind": nil}
+
+	result, err := qprintf(q, Params{"kind": "a", "limit": LimitParam(5)}.WithDefaults(defaults))
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE status = 'active' AND kind = 'a' LIMIT 5", result)
+
+	result, err = qprintf(q, Params(nil).WithDefaults(defaults))
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE status = 'active' AND kind = NULL LIMIT 20", result)
+
+	result, err = qprintf(q, Params{"status": "it's; DROP TABLE t", "kind": nil}.WithDefaults(defaults))
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE status = 'it''s; DROP TABLE t' AND kind = NULL LIMIT 20", result)
+}
+
+func TestQprintfCommaListParamForms(t *testing.T) {
+	var nilList *CommaListParam
+	list := CommaListParam{1, "a"}
+
+	result, err := qprintf("IN (:a), IN (:b), VALUES :c, WHERE (a, b) IN (:d)", Params{
+		"a": &list,
+		"b": nilList,
+		"c": CommaListParam{CommaListParam{1, "x"}, &CommaListParam{2, nil}},
+		"d": CommaListParam{CommaListParam{CommaListParam{1, 2}, 3}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "IN (1, 'a'), IN (NULL), VALUES (1, 'x'), (2, NULL), WHERE (a, b) IN (((1, 2), 3))", result)
+}
+
+func TestQprintfRowValuesParam(t *testing.T) {
+	result, err := qprintf("INSERT INTO test (id, name, amount) VALUES :rows", Params{
+		"rows": RowValuesParam{
+			{1, "it's", decimal.RequireFromString("1.50")},
+			{int64(2), nil, true},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO test (id, name, amount) VALUES (1, 'it''s', 1.5), (2, NULL, true)", result)
+
+	result, err = qprintf("VALUES :rows", Params{"rows": RowValuesParam(nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, "VALUES NULL", result)
+
+	_, err = qprintf("VALUES :rows", Params{"rows": RowValuesParam{}})
+	assert.Error(t, err)
+
+	_, err = qprintf("VALUES :rows", Params{"rows": RowValuesParam{{1}, {}}})
+	assert.Error(t, err)
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	cases := []struct {
+		in  string
+		out string
+	}{
+		{"plain", `'plain'`},
+		{"", `''`},
+		{"it's", `'it''s'`},
+		{`C:\dir`, `E'C:\\dir'`},
+		{`\'`, `E'\\'''`},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			assert.Equal(t, c.out, QuoteLiteral(c.in))
+		})
+	}
+
+	assert.Equal(t, `"users"`, QuoteIdentifier("users"))
+	assert.Equal(t, `"Order ""Items"""`, QuoteIdentifier(`Order "Items"`))
+}
+
+type testLogger []string
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, args...))
+}
+
+func TestDebugLogger(t *testing.T) {
+	var logged testLogger
+	DebugLogger = &logged
+	defer func() { DebugLogger = nil }()
+
+	dbh, _ := newFakeDB(t, nil)
+	_, err := Exec(context.Background(), dbh, "UPDATE users SET password = :password WHERE id = :id", Params{"id": 1, "password": "secret"})
+	assert.NoError(t, err)
+	_, err = Exec(context.Background(), dbh, "UPDATE users SET a = :missing", nil)
+	assert.Error(t, err)
+
+	err = ExecNonTx(context.Background(), dbh, "VACUUM users")
+	assert.NoError(t, err)
+	_, err = CopyFrom(context.Background(), dbh, "users", []string{"id"}, [][]interface{}{{1}})
+	assert.NoError(t, err)
+	run, closeStmt, err := PreparedQuery[scanTestModel](context.Background(), dbh, "SELECT id, name FROM users WHERE id = :id")
+	assert.NoError(t, err)
+	defer closeStmt()
+	_, err = run(Params{"id": 1})
+	assert.NoError(t, err)
+
+	assert.Equal(t, testLogger{
+		"db: executing query with 2 params: UPDATE users SET password = :password WHERE id = :id",
+		"db: executing query with 0 params: VACUUM users",
+		"db: executing query with 0 params: COPY users (id) FROM STDIN",
+		"db: executing query with 1 params: SELECT id, name FROM users WHERE id = :id",
+	}, logged)
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SQLSTATE codes the package needs to distinguish
+const (
+	sqlStateFeatureNotSupported = "0A000"
+	sqlStateSyntaxError         = "42601"
+	sqlStateUndefinedFunction   = "42883"
+)
+
+// sqlState extracts SQLSTATE code from driver error without depending on
+// a particular driver: pgx (*pgconn.PgError) and lib/pq (*pq.Error) both
+// provide SQLState() method, older lib/pq versions only have Code field
+func sqlState(err error) string {
+	var withState interface{ SQLState() string }
+	if errors.As(err, &withState) {
+		return withState.SQLState()
+	}
+	for ; err != nil; err = errors.Unwrap(err) {
+		if code, ok := stringField(err, "Code"); ok {
+			return code
+		}
+	}
+	return ""
+}
+
+// ConstraintName returns name of the constraint violated, as reported by
+// pgx (PgError.ConstraintName) or lib/pq (Error.Constraint) error
+func ConstraintName(err error) (string, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		for _, field := range []string{"ConstraintName", "Constraint"} {
+			if name, ok := stringField(err, field); ok && name != "" {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// stringField reads string-kinded field of struct or pointer to struct
+func stringField(v interface{}, name string) (string, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", false
+	}
+	f := rv.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return "", false
+	}
+	return f.String(), true
+}
+
+// ErrorClass is a coarse category of database error
+type ErrorClass int
+
+const (
+	ClassUnknown ErrorClass = iota
+	ClassUniqueViolation
+	ClassForeignKey
+	ClassCheck
+	ClassNotNull
+	ClassSerialization
+	ClassDeadlock
+	ClassConnection
+	ClassSyntax
+)
+
+var errorClassNames = [...]string{
+	ClassUnknown:         "unknown",
+	ClassUniqueViolation: "unique_violation",
+	ClassForeignKey:      "foreign_key_violation",
+	ClassCheck:           "check_violation",
+	ClassNotNull:         "not_null_violation",
+	ClassSerialization:   "serialization_failure",
+	ClassDeadlock:        "deadlock_detected",
+	ClassConnection:      "connection_exception",
+	ClassSyntax:          "syntax_error",
+}
+
+func (c ErrorClass) String() string {
+	if c < 0 || int(c) >= len(errorClassNames) {
+		return "ErrorClass(" + strconv.Itoa(int(c)) + ")"
+	}
+	return errorClassNames[c]
+}
+
+// Classify maps error to its class by SQLSTATE. Connection class covers whole
+// SQLSTATE class 08 and driver.ErrBadConn, syntax class covers whole class 42.
+// Nil and unrecognized errors are ClassUnknown.
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return ClassConnection
+	}
+	code := sqlState(err)
+	switch code {
+	case "23505":
+		return ClassUniqueViolation
+	case "23503":
+		return ClassForeignKey
+	case "23514":
+		return ClassCheck
+	case "23502":
+		return ClassNotNull
+	case "40001":
+		return ClassSerialization
+	case "40P01":
+		return ClassDeadlock
+	}
+	switch {
+	case strings.HasPrefix(code, "08"):
+		return ClassConnection
+	case strings.HasPrefix(code, "42"):
+		return ClassSyntax
+	}
+	return ClassUnknown
+}
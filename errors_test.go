@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pgxError mimics *pgconn.PgError
+type pgxError struct {
+	Code           string
+	Message        string
+	ConstraintName string
+}
+
+func (e *pgxError) Error() string {
+	return e.Message + " (SQLSTATE " + e.Code + ")"
+}
+
+func (e *pgxError) SQLState() string {
+	return e.Code
+}
+
+// pqErrorCode and pqError mimic lib/pq types without SQLState() method
+type pqErrorCode string
+
+type pqError struct {
+	Code       pqErrorCode
+	Message    string
+	Constraint string
+}
+
+func (e *pqError) Error() string {
+	return "pq: " + e.Message
+}
+
+func TestSQLState(t *testing.T) {
+	var cases = []struct {
+		err      error
+		expected string
+	}{
+		{&pgxError{Code: "42883"}, "42883"},
+		{&pqError{Code: "23505"}, "23505"},
+		{wrapError(&pqError{Code: "40001"}, "SELECT 1", nil), "40001"},
+		{fmt.Errorf("wrapped: %w", &pgxError{Code: "0A000"}), "0A000"},
+		{fmt.Errorf("plain error"), ""},
+		{nil, ""},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			assert.Equal(t, c.expected, sqlState(c.err))
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	var cases = []struct {
+		err      error
+		expected ErrorClass
+	}{
+		{&pgxError{Code: "23505"}, ClassUniqueViolation},
+		{&pqError{Code: "23503"}, ClassForeignKey},
+		{&pgxError{Code: "23514"}, ClassCheck},
+		{&pqError{Code: "23502"}, ClassNotNull},
+		{wrapError(&pgxError{Code: "40001"}, "UPDATE t SET a = 1", nil), ClassSerialization},
+		{&pqError{Code: "40P01"}, ClassDeadlock},
+		{&pgxError{Code: "08006"}, ClassConnection},
+		{fmt.Errorf("query failed: %w", driver.ErrBadConn), ClassConnection},
+		{&pqError{Code: "42601"}, ClassSyntax},
+		{&pgxError{Code: "42P01"}, ClassSyntax},
+		{&pgxError{Code: "22012"}, ClassUnknown},
+		{fmt.Errorf("plain error"), ClassUnknown},
+		{nil, ClassUnknown},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			assert.Equal(t, c.expected, Classify(c.err))
+		})
+	}
+}
+
+func TestConstraintName(t *testing.T) {
+	var cases = []struct {
+		err      error
+		expected string
+		ok       bool
+	}{
+		{wrapError(&pgxError{Code: "23505", ConstraintName: "users_email_key"}, "INSERT", nil), "users_email_key", true},
+		{wrapError(&pqError{Code: "23503", Constraint: "orders_user_id_fkey"}, "INSERT", nil), "orders_user_id_fkey", true},
+		{&pgxError{Code: "42601"}, "", false},
+		{fmt.Errorf("plain error"), "", false},
+		{nil, "", false},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			name, ok := ConstraintName(c.err)
+			assert.Equal(t, c.expected, name)
+			assert.Equal(t, c.ok, ok)
+		})
+	}
+}
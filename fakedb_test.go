@@ -0,0 +1,276 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDriverName is the name the scripted test driver is registered under
+const fakeDriverName = "dbtest"
+
+var (
+	fakeServers   sync.Map // dsn -> *fakeServer
+	fakeServerSeq int64
+)
+
+func init() {
+	sql.Register(fakeDriverName, fakeDriver{})
+}
+
+// fakeCall describes a single statement received by the fake driver
+type fakeCall struct {
+	Ctx      context.Context
+	Query    string
+	Args     []driver.NamedValue
+	InTx     bool
+	ReadOnly bool
+}
+
+// fakeResult is what a handler returns for a statement
+type fakeResult struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	// NoRowsAffected makes RowsAffected of the result fail
+	NoRowsAffected bool
+}
+
+type fakeHandler func(c *fakeCall) (*fakeResult, error)
+
+// fakeServer keeps the script and the statement log shared by all
+// connections opened with the same DSN
+type fakeServer struct {
+	mu       sync.Mutex
+	handler  fakeHandler
+	pingErrs int
+	pings    int
+	prepares int
+	log      []string
+	openRows int32
+}
+
+func newFakeServer(t *testing.T, handler fakeHandler) (*fakeServer, string) {
+	dsn := fmt.Sprintf("fake-%d", atomic.AddInt64(&fakeServerSeq, 1))
+	s := &fakeServer{handler: handler}
+	fakeServers.Store(dsn, s)
+	t.Cleanup(func() { fakeServers.Delete(dsn) })
+	return s, dsn
+}
+
+func newFakeDB(t *testing.T, handler fakeHandler) (*sql.DB, *fakeServer) {
+	s, dsn := newFakeServer(t, handler)
+	dbh, err := sql.Open(fakeDriverName, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbh.Close() })
+	return dbh, s
+}
+
+// Log returns all statements received so far, including BEGIN/COMMIT/ROLLBACK
+func (s *fakeServer) Log() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.log...)
+}
+
+func (s *fakeServer) OpenRows() int {
+	return int(atomic.LoadInt32(&s.openRows))
+}
+
+func (s *fakeServer) record(stmt string) {
+	s.mu.Lock()
+	s.log = append(s.log, stmt)
+	s.mu.Unlock()
+}
+
+func (s *fakeServer) handle(c *fakeCall) (*fakeResult, error) {
+	s.record(c.Query)
+	if s.handler == nil {
+		return &fakeResult{}, nil
+	}
+	res, err := s.handler(c)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		res = &fakeResult{}
+	}
+	return res, nil
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	s, ok := fakeServers.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("unknown fake dsn %q", dsn)
+	}
+	return &fakeConn{server: s.(*fakeServer)}, nil
+}
+
+type fakeConn struct {
+	server   *fakeServer
+	inTx     bool
+	readOnly bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.server.mu.Lock()
+	c.server.prepares++
+	c.server.mu.Unlock()
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.server.record("BEGIN READ ONLY")
+	} else {
+		c.server.record("BEGIN")
+	}
+	c.inTx = true
+	c.readOnly = opts.ReadOnly
+	return &fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	c.server.pings++
+	if c.server.pingErrs > 0 {
+		c.server.pingErrs--
+		return fmt.Errorf("the database system is starting up")
+	}
+	return nil
+}
+
+func (c *fakeConn) call(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error) {
+	return c.server.handle(&fakeCall{
+		Ctx:      ctx,
+		Query:    query,
+		Args:     args,
+		InTx:     c.inTx,
+		ReadOnly: c.readOnly,
+	})
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.call(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if res.NoRowsAffected {
+		return driver.ResultNoRows, nil
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.call(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&c.server.openRows, 1)
+	return &fakeRows{server: c.server, res: res}, nil
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.conn.server.record("COMMIT")
+	tx.conn.inTx, tx.conn.readOnly = false, false
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.server.record("ROLLBACK")
+	tx.conn.inTx, tx.conn.readOnly = false, false
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+type fakeRows struct {
+	server *fakeServer
+	res    *fakeResult
+	pos    int
+	closed bool
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.res.Columns
+}
+
+func (r *fakeRows) Close() error {
+	if !r.closed {
+		r.closed = true
+		atomic.AddInt32(&r.server.openRows, -1)
+	}
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.res.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.res.Rows[r.pos])
+	r.pos++
+	return nil
+}
+
+// rowsOf is a shortcut for building a result set in handlers
+func rowsOf(columns string, rows ...[]driver.Value) *fakeResult {
+	return &fakeResult{Columns: strings.Split(columns, ","), Rows: rows}
+}
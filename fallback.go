@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// QuerySpec is a query together with its parameters
+type QuerySpec struct {
+	SQL    string
+	Params Params
+}
+
+// QueryFirstSupported runs candidates in order and returns rows of the first
+// one the server accepts. It falls back to the next candidate only when the
+// query fails with feature_not_supported (0A000), syntax_error (42601) or
+// undefined_function (42883), any other error is returned immediately.
+func QueryFirstSupported(ctx context.Context, db Queryable, candidates []QuerySpec) (*sql.Rows, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("no query candidates given")
+	}
+	var err error
+	for _, c := range candidates {
+		var rows *sql.Rows
+		rows, err = Query(ctx, db, c.SQL, c.Params)
+		if err == nil {
+			return rows, nil
+		}
+		if !isUnsupportedQueryError(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func isUnsupportedQueryError(err error) bool {
+	switch sqlState(err) {
+	case sqlStateFeatureNotSupported, sqlStateSyntaxError, sqlStateUndefinedFunction:
+		return true
+	}
+	return false
+}
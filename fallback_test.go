@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryFirstSupported(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if strings.Contains(c.Query, "jsonb_path_query_first") {
+			return nil, &pgxError{Code: "42883", Message: "function jsonb_path_query_first does not exist"}
+		}
+		return rowsOf("value", []driver.Value{"x"}), nil
+	})
+
+	rows, err := QueryFirstSupported(context.Background(), dbh, []QuerySpec{
+		{`SELECT jsonb_path_query_first(data, :path) FROM test`, Params{"path": "$.a"}},
+		{`SELECT data #> :path FROM test`, Params{"path": "{a}"}},
+	})
+	if assert.NoError(t, err) {
+		defer rows.Close()
+		assert.True(t, rows.Next())
+	}
+	assert.Equal(t, []string{
+		`SELECT jsonb_path_query_first(data, '$.a') FROM test`,
+		`SELECT data #> '{a}' FROM test`,
+	}, s.Log())
+}
+
+func TestQueryFirstSupportedOtherError(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return nil, &pgxError{Code: "42P01", Message: "relation does not exist"}
+	})
+
+	_, err := QueryFirstSupported(context.Background(), dbh, []QuerySpec{
+		{`SELECT 1 FROM a`, nil},
+		{`SELECT 1 FROM b`, nil},
+	})
+	assert.Error(t, err)
+	assert.Len(t, s.Log(), 1)
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValuesTable renders rows as a VALUES table expression like
+// (VALUES (1, 'a'), (2, 'b')) AS alias(col1, col2)
+func ValuesTable(alias string, columns []string, rows [][]interface{}) (string, error) {
+	if err := checkIdentifier(alias); err != nil {
+		return "", err
+	}
+	if len(columns) == 0 {
+		return "", errors.New("no columns given")
+	}
+	for _, col := range columns {
+		if err := checkIdentifier(col); err != nil {
+			return "", err
+		}
+	}
+	if len(rows) == 0 {
+		return "", errors.New("VALUES requires at least one row")
+	}
+	tuples, err := renderTuples(rows, len(columns))
+	if err != nil {
+		return "", err
+	}
+	return "(VALUES " + tuples + ") AS " + alias + "(" + strings.Join(columns, ", ") + ")", nil
+}
+
+// TupleIn renders multi-column IN predicate like
+// (a, b) IN ((1, 'x'), (2, 'y')), no rows give false
+func TupleIn(columns []string, rows [][]interface{}) (string, error) {
+	if len(columns) == 0 {
+		return "", errors.New("no columns given")
+	}
+	for _, col := range columns {
+		if err := checkQualifiedIdentifier(col); err != nil {
+			return "", err
+		}
+	}
+	if len(rows) == 0 {
+		return "false", nil
+	}
+	tuples, err := renderTuples(rows, len(columns))
+	if err != nil {
+		return "", err
+	}
+	return "(" + strings.Join(columns, ", ") + ") IN (" + tuples + ")", nil
+}
+
+// renderTuples renders rows as comma separated list of parenthesized tuples,
+// every row must have arity values
+func renderTuples(rows [][]interface{}, arity int) (string, error) {
+	var b strings.Builder
+	for i, row := range rows {
+		if len(row) != arity {
+			return "", fmt.Errorf("row %d has %d values, expected %d", i+1, len(row), arity)
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for j, v := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			s, err := toDbValue(v)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		}
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+// SetClause renders params keys as "a = :a, b = :b" for UPDATE ... SET,
+// keys are sorted so the same params always give the same SQL
+func SetClause(params Params) (string, error) {
+	if len(params) == 0 {
+		return "", errors.New("no columns to set")
+	}
+	return joinEq(params, " = ", ", ")
+}
+
+// WhereEq renders params keys as "a = :a AND b = :b" condition, keys are
+// sorted so the same params always give the same SQL. Empty params give TRUE.
+func WhereEq(params Params) (string, error) {
+	if len(params) == 0 {
+		return "TRUE", nil
+	}
+	return joinEq(params, " = ", " AND ")
+}
+
+// WhereEqNullSafe is WhereEq comparing with IS NOT DISTINCT FROM,
+// so NULL param matches NULL column
+func WhereEqNullSafe(params Params) (string, error) {
+	if len(params) == 0 {
+		return "TRUE", nil
+	}
+	return joinEq(params, " IS NOT DISTINCT FROM ", " AND ")
+}
+
+// FilterStruct renders "a = :a AND b = :b" condition from fields of filter
+// struct v which are set, i.e. not nil and not zero, along with params to
+// run it with. Fields map to columns the same way they do when scanning.
+// Pointer fields are dereferenced, so pointer to zero value still filters.
+// No set fields give TRUE.
+func FilterStruct(v interface{}) (where string, params Params, err error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", nil, fmt.Errorf("filter must be struct, got %T", v)
+	}
+	params = Params{}
+	for _, field := range getStructInfo(rv.Type()).columns {
+		fv := rv.FieldByIndex(field.index)
+		if fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			fv = fv.Elem()
+		}
+		params[field.name] = fv.Interface()
+	}
+	where, err = WhereEq(params)
+	if err != nil {
+		return "", nil, err
+	}
+	return where, params, nil
+}
+
+func joinEq(params Params, op, sep string) (string, error) {
+	keys := sortedKeys(params)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if err := checkIdentifier(k); err != nil {
+			return "", err
+		}
+		parts[i] = k + op + placeholder(k)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// Coalesce renders "COALESCE(t.col, :t_col_default) AS col" select list
+// expression along with params carrying defaultValue, to be merged into
+// query params. Column may be qualified by table or alias.
+func Coalesce(column string, defaultValue interface{}) (expr string, params Params, err error) {
+	if err := checkQualifiedIdentifier(column); err != nil {
+		return "", nil, err
+	}
+	if _, err := toDbValue(defaultValue); err != nil {
+		return "", nil, fmt.Errorf("default of %s: %w", column, err)
+	}
+	alias := column[strings.LastIndexByte(column, '.')+1:]
+	param := strings.NewReplacer(".", "_", "$", "_").Replace(column) + "_default"
+	expr = "COALESCE(" + column + ", " + placeholder(param) + ") AS " + alias
+	return expr, Params{param: defaultValue}, nil
+}
+
+// ExcludedSet renders "a = EXCLUDED.a, b = EXCLUDED.b" assignments for
+// INSERT ... ON CONFLICT ... DO UPDATE SET, columns which are not plain
+// identifiers are quoted
+func ExcludedSet(columns []string) string {
+	parts := make([]string, len(columns))
+	for i, col := range columns {
+		if !isIdentifier(col) {
+			col = QuoteIdentifier(col)
+		}
+		parts[i] = col + " = EXCLUDED." + col
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortedKeys returns params keys in sorted order
+func sortedKeys(params Params) []string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValuesTable(t *testing.T) {
+	result, err := ValuesTable("v", []string{"id", "val"}, [][]interface{}{
+		{1, "a"},
+		{2, "it's"},
+		{3, nil},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "(VALUES (1, 'a'), (2, 'it''s'), (3, NULL)) AS v(id, val)", result)
+}
+
+func TestValuesTableErrors(t *testing.T) {
+	_, err := ValuesTable("v", []string{"id", "val"}, [][]interface{}{{1, "a"}, {2}})
+	assert.EqualError(t, err, "row 2 has 1 values, expected 2")
+
+	_, err = ValuesTable("v", []string{"id"}, nil)
+	assert.Error(t, err)
+
+	_, err = ValuesTable("v", []string{"id; DROP TABLE users"}, [][]interface{}{{1}})
+	assert.Error(t, err)
+
+	_, err = ValuesTable("1v", []string{"id"}, [][]interface{}{{1}})
+	assert.Error(t, err)
+}
+
+func TestSetClauseAndWhereEq(t *testing.T) {
+	params := Params{"name": "a", "id": 1, "status": "active", "created_at": nil, "balance": 10}
+
+	for i := 0; i < 20; i++ {
+		set, err := SetClause(params)
+		assert.NoError(t, err)
+		assert.Equal(t, "balance = :balance, created_at = :created_at, id = :id, name = :name, status = :status", set)
+
+		where, err := WhereEq(params)
+		assert.NoError(t, err)
+		assert.Equal(t, "balance = :balance AND created_at = :created_at AND id = :id AND name = :name AND status = :status", where)
+	}
+
+	where, err := WhereEq(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "TRUE", where)
+
+	_, err = SetClause(nil)
+	assert.Error(t, err)
+
+	_, err = WhereEq(Params{"id = 1 OR 1": 1})
+	assert.Error(t, err)
+}
+
+func TestWhereEqNullSafe(t *testing.T) {
+	params := Params{"id": 1, "deleted_at": nil}
+
+	where, err := WhereEqNullSafe(params)
+	assert.NoError(t, err)
+	assert.Equal(t, "deleted_at IS NOT DISTINCT FROM :deleted_at AND id IS NOT DISTINCT FROM :id", where)
+
+	sql, err := qprintf(where, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "deleted_at IS NOT DISTINCT FROM NULL AND id IS NOT DISTINCT FROM 1", sql)
+
+	where, err = WhereEq(params)
+	assert.NoError(t, err)
+	sql, err = qprintf(where, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "deleted_at = NULL AND id = 1", sql)
+}
+
+func TestFilterStruct(t *testing.T) {
+	type filter struct {
+		ID       int64   `sql:"id"`
+		Status   string  `sql:"status"`
+		Archived *bool   `sql:"archived"`
+		ShopID   *int64  `sql:"shop_id"`
+		Comment  string  `sql:"-"`
+		Tags     []int64 `sql:"tags"`
+	}
+	archived := false
+	where, params, err := FilterStruct(&filter{Status: "active", Archived: &archived, Comment: "skipped"})
+	assert.NoError(t, err)
+	assert.Equal(t, "archived = :archived AND status = :status", where)
+	assert.Equal(t, Params{"archived": false, "status": "active"}, params)
+
+	sql, err := qprintf("SELECT * FROM test WHERE "+where, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM test WHERE archived = false AND status = 'active'", sql)
+
+	where, params, err = FilterStruct(filter{})
+	assert.NoError(t, err)
+	assert.Equal(t, "TRUE", where)
+	assert.Empty(t, params)
+
+	_, _, err = FilterStruct(map[string]interface{}{"id": 1})
+	assert.Error(t, err)
+}
+
+func TestTupleIn(t *testing.T) {
+	result, err := TupleIn([]string{"a", "t.b"}, [][]interface{}{{1, "x"}, {2, "it's"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "(a, t.b) IN ((1, 'x'), (2, 'it''s'))", result)
+
+	result, err = TupleIn([]string{"a", "b"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "false", result)
+
+	_, err = TupleIn([]string{"a", "b"}, [][]interface{}{{1}})
+	assert.Error(t, err)
+
+	_, err = TupleIn([]string{"a) OR (1"}, [][]interface{}{{1}})
+	assert.Error(t, err)
+}
+
+func TestCoalesce(t *testing.T) {
+	expr, params, err := Coalesce("u.name", "n/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "COALESCE(u.name, :u_name_default) AS name", expr)
+	assert.Equal(t, Params{"u_name_default": "n/a"}, params)
+
+	sql, err := qprintf("SELECT "+expr+" FROM users u", params)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT COALESCE(u.name, 'n/a') AS name FROM users u", sql)
+
+	expr, params, err = Coalesce("balance", decimal.RequireFromString("0.00"))
+	assert.NoError(t, err)
+	sql, err = qprintf("SELECT "+expr+" FROM accounts", params)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT COALESCE(balance, 0) AS balance FROM accounts", sql)
+
+	_, _, err = Coalesce("name); DROP TABLE users; --", "")
+	assert.Error(t, err)
+
+	_, _, err = Coalesce("name", make(chan int))
+	assert.Error(t, err)
+}
+
+func TestExcludedSet(t *testing.T) {
+	assert.Equal(t, "name = EXCLUDED.name, balance = EXCLUDED.balance, \"Updated At\" = EXCLUDED.\"Updated At\"",
+		ExcludedSet([]string{"name", "balance", "Updated At"}))
+	assert.Equal(t, `"a = 1; --" = EXCLUDED."a = 1; --"`, ExcludedSet([]string{"a = 1; --"}))
+	assert.Equal(t, "", ExcludedSet(nil))
+}
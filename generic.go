@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// TotalCountColumn is the column QueryWithTotal reads total count from
+const TotalCountColumn = "total_count"
+
+// QueryWithTotal scans a page of rows along with the total number of rows
+// matched regardless of LIMIT/OFFSET. The query must select the total with a
+// window function, e.g.
+//
+//	SELECT id, name, count(*) OVER() AS total_count FROM t ORDER BY id LIMIT 10
+//
+// so the total comes with every row and no second query is needed. An empty
+// page has total 0.
+func QueryWithTotal[T any](ctx context.Context, db Queryable, q string, params Params, opts ...ScanOption) ([]T, int, error) {
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var total int
+	cfg := newScanConfig(opts)
+	cfg.columnDests = map[string]interface{}{TotalCountColumn: &total}
+
+	var items []T
+	for rows.Next() {
+		if items == nil {
+			if err = checkTotalColumn(rows); err != nil {
+				return nil, 0, wrapError(err, q, params)
+			}
+		}
+		var item T
+		if err = scanStruct(rows, &item, cfg); err != nil {
+			return nil, 0, wrapError(err, q, params)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, wrapError(err, q, params)
+	}
+	return items, total, nil
+}
+
+// checkTotalColumn fails when rows don't have TotalCountColumn
+func checkTotalColumn(rows *sql.Rows) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for _, col := range cols {
+		if col == TotalCountColumn {
+			return nil
+		}
+	}
+	return fmt.Errorf("query doesn't select %s column", TotalCountColumn)
+}
+
+// QueryJSONEach unmarshals the single JSON column of every row into T and
+// passes it to fn, iteration stops at the first error returned by fn
+func QueryJSONEach[T any](ctx context.Context, db Queryable, q string, params Params, fn func(T) error) error {
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item T
+		if err = ScanJSONRowsIntoStruct(rows, &item); err != nil {
+			return wrapError(err, q, params)
+		}
+		if err = fn(item); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return wrapError(err, q, params)
+	}
+	return nil
+}
+
+// hasTrailingLimit reports whether q ends with LIMIT clause, optionally
+// followed by OFFSET, or with FETCH FIRST/NEXT ... ROWS ONLY clause, which
+// values are numbers or placeholders. Limit of a subquery is followed by )
+// and doesn't match.
+func hasTrailingLimit(q string) bool {
+	value := `(\d+|` + regexp.QuoteMeta(string(ParamPrefix)) + `\w+)`
+	limit := `\blimit\s+(` + value + `|all)(\s+offset\s+` + value + `)?`
+	fetch := `\bfetch\s+(first|next)(\s+` + value + `)?\s+rows?\s+only`
+	re := regexp.MustCompile(`(?i)(` + limit + `|` + fetch + `)\s*$`)
+	return re.MatchString(q)
+}
+
+// trimTrailingComments removes comments and whitespace from the end of q
+func trimTrailingComments(q string) string {
+	end := 0
+	for i := 0; i < len(q); i++ {
+		next := quotedEnd(q, i)
+		switch {
+		case next == -1:
+			return q
+		case next != i:
+			if q[i] != '-' && q[i] != '/' {
+				end = next
+			}
+			i = next - 1
+		case q[i] != ' ' && q[i] != '\t' && q[i] != '\n' && q[i] != '\r':
+			end = i + 1
+		}
+	}
+	return q[:end]
+}
+
+// QueryFirst scans the first row of q into T, nil is returned when there
+// are no rows. LIMIT 1 is appended unless q already ends with LIMIT or
+// FETCH FIRST clause. Trailing comments are removed.
+func QueryFirst[T any](ctx context.Context, db Queryable, q string, params Params, opts ...ScanOption) (*T, error) {
+	q = trimStatement(trimTrailingComments(q))
+	if !hasTrailingLimit(q) {
+		q += " LIMIT 1"
+	}
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, wrapError(err, q, params)
+		}
+		return nil, nil
+	}
+	item := new(T)
+	if err = scanStruct(rows, item, newScanConfig(opts)); err != nil {
+		return nil, wrapError(err, q, params)
+	}
+	return item, nil
+}
+
+// QueryGroupBy scans rows into V and groups them by keyFn, rows of
+// every group keep their order
+func QueryGroupBy[K comparable, V any](ctx context.Context, db Queryable, q string, params Params, keyFn func(V) K, opts ...ScanOption) (map[K][]V, error) {
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cfg := newScanConfig(opts)
+	groups := make(map[K][]V)
+	for rows.Next() {
+		var item V
+		if err = scanStruct(rows, &item, cfg); err != nil {
+			return nil, wrapError(err, q, params)
+		}
+		key := keyFn(item)
+		groups[key] = append(groups[key], item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError(err, q, params)
+	}
+	return groups, nil
+}
+
+// QueryRows scans every row of q into T, it's the typed counterpart of
+// QueryRowsIntoSlice
+func QueryRows[T any](ctx context.Context, db Queryable, q string, params Params, opts ...ScanOption) ([]T, error) {
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cfg := newScanConfig(opts)
+	var items []T
+	for rows.Next() {
+		var item T
+		if err = scanStruct(rows, &item, cfg); err != nil {
+			return nil, wrapError(err, q, params)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, wrapError(err, q, params)
+	}
+	return items, nil
+}
+
+// QueryOne scans the first row of q into T like QueryRowIntoStruct does,
+// sql.ErrNoRows is returned when there are no rows
+func QueryOne[T any](ctx context.Context, db Queryable, q string, params Params, opts ...ScanOption) (T, error) {
+	var item T
+	if err := QueryRowIntoStruct(ctx, db, q, params, &item, opts...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return item, nil
+}
+
+// QueryEach scans rows of q into T one by one and passes them to fn without
+// keeping them in memory, iteration stops at the first error returned by fn
+func QueryEach[T any](ctx context.Context, db Queryable, q string, params Params, fn func(T) error, opts ...ScanOption) error {
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cfg := newScanConfig(opts)
+	for rows.Next() {
+		var item T
+		if err = scanStruct(rows, &item, cfg); err != nil {
+			return wrapError(err, q, params)
+		}
+		if err = fn(item); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return wrapError(err, q, params)
+	}
+	return nil
+}
+
+// ErrMultipleRows is returned by QueryExactlyOne when query returns
+// more than one row
+var ErrMultipleRows = errors.New("query returned more than one row")
+
+// QueryExactlyOne scans the only row of q into T, sql.ErrNoRows is returned
+// when there are no rows and ErrMultipleRows when there is more than one
+func QueryExactlyOne[T any](ctx context.Context, db Queryable, q string, params Params, opts ...ScanOption) (T, error) {
+	var item T
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return item, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return item, wrapError(err, q, params)
+		}
+		return item, sql.ErrNoRows
+	}
+	if err = scanStruct(rows, &item, newScanConfig(opts)); err != nil {
+		var zero T
+		return zero, wrapError(err, q, params)
+	}
+	if rows.Next() {
+		var zero T
+		return zero, wrapError(ErrMultipleRows, q, params)
+	}
+	if err = rows.Err(); err != nil {
+		var zero T
+		return zero, wrapError(err, q, params)
+	}
+	return item, nil
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryWithTotal(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name,total_count",
+			[]driver.Value{int64(1), "first", int64(42)},
+			[]driver.Value{int64(2), "second", int64(42)},
+		), nil
+	})
+
+	q := "SELECT id, name, count(*) OVER() AS total_count FROM test ORDER BY id LIMIT :limit"
+	items, total, err := QueryWithTotal[scanTestModel](context.Background(), dbh, q, Params{"limit": 2}, RequireAllFields())
+	assert.NoError(t, err)
+	assert.Equal(t, 42, total)
+	assert.Equal(t, []scanTestModel{{1, "first"}, {2, "second"}}, items)
+	assert.Equal(t, []string{"SELECT id, name, count(*) OVER() AS total_count FROM test ORDER BY id LIMIT 2"}, s.Log())
+}
+
+func TestQueryWithTotalMissingColumn(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name", []driver.Value{int64(1), "first"}), nil
+	})
+
+	_, _, err := QueryWithTotal[scanTestModel](context.Background(), dbh, "SELECT id, name FROM test ORDER BY id LIMIT 10", nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "total_count")
+	}
+}
+
+func TestQueryWithTotalEmpty(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name,total_count"), nil
+	})
+
+	items, total, err := QueryWithTotal[scanTestModel](context.Background(), dbh, "SELECT id, name, count(*) OVER() AS total_count FROM test", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, items)
+}
+
+func TestQueryJSONEach(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("data",
+			[]driver.Value{[]byte(`{"id": 1, "name": "first"}`)},
+			[]driver.Value{[]byte(`{"id": 2, "name": "second"}`)},
+			[]driver.Value{[]byte(`{"id": 3, "name": "third"}`)},
+		), nil
+	})
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	q := "SELECT row_to_json(t) AS data FROM test t"
+
+	var items []item
+	err := QueryJSONEach(context.Background(), dbh, q, nil, func(i item) error {
+		items = append(items, i)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{1, "first"}, {2, "second"}, {3, "third"}}, items)
+
+	items = nil
+	stop := errors.New("stop")
+	err = QueryJSONEach(context.Background(), dbh, q, nil, func(i item) error {
+		items = append(items, i)
+		if i.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []item{{1, "first"}, {2, "second"}}, items)
+	assert.Equal(t, 0, s.OpenRows())
+}
+
+func TestQueryFirst(t *testing.T) {
+	found := true
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if !found {
+			return rowsOf("id,name"), nil
+		}
+		return rowsOf("id,name", []driver.Value{int64(2), "latest"}), nil
+	})
+	ctx := context.Background()
+
+	item, err := QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test ORDER BY id DESC", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &scanTestModel{2, "latest"}, item)
+
+	item, err = QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test ORDER BY id DESC\n\tlimit :limit OFFSET 1", Params{"limit": 5})
+	assert.NoError(t, err)
+	assert.NotNil(t, item)
+
+	found = false
+	item, err = QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test WHERE name = :name", Params{"name": "limit 5"})
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+
+	_, err = QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test WHERE id IN (SELECT id FROM other LIMIT 5)", nil)
+	assert.NoError(t, err)
+
+	_, err = QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test; -- latest\n/* one */ ", nil)
+	assert.NoError(t, err)
+
+	_, err = QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test LIMIT ALL -- all", nil)
+	assert.NoError(t, err)
+
+	_, err = QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test ORDER BY id OFFSET 2 ROWS FETCH FIRST 3 ROWS ONLY", nil)
+	assert.NoError(t, err)
+
+	_, err = QueryFirst[scanTestModel](ctx, dbh, "SELECT id, name FROM test fetch next row only", nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"SELECT id, name FROM test ORDER BY id DESC LIMIT 1",
+		"SELECT id, name FROM test ORDER BY id DESC\n\tlimit 5 OFFSET 1",
+		"SELECT id, name FROM test WHERE name = 'limit 5' LIMIT 1",
+		"SELECT id, name FROM test WHERE id IN (SELECT id FROM other LIMIT 5) LIMIT 1",
+		"SELECT id, name FROM test LIMIT 1",
+		"SELECT id, name FROM test LIMIT ALL",
+		"SELECT id, name FROM test ORDER BY id OFFSET 2 ROWS FETCH FIRST 3 ROWS ONLY",
+		"SELECT id, name FROM test fetch next row only",
+	}, s.Log())
+}
+
+func TestQueryGroupBy(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,category,name",
+			[]driver.Value{int64(1), "fruit", "apple"},
+			[]driver.Value{int64(2), "vegetable", "carrot"},
+			[]driver.Value{int64(3), "fruit", "pear"},
+		), nil
+	})
+	type item struct {
+		ID       int64  `sql:"id"`
+		Category string `sql:"category"`
+		Name     string `sql:"name"`
+	}
+
+	groups, err := QueryGroupBy(context.Background(), dbh, "SELECT id, category, name FROM items ORDER BY id", nil, func(i item) string {
+		return i.Category
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]item{
+		"fruit":     {{1, "fruit", "apple"}, {3, "fruit", "pear"}},
+		"vegetable": {{2, "vegetable", "carrot"}},
+	}, groups)
+}
+
+func TestQueryRows(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name",
+			[]driver.Value{int64(1), "first"},
+			[]driver.Value{int64(2), "second"},
+			[]driver.Value{int64(3), "third"},
+		), nil
+	})
+
+	items, err := QueryRows[scanTestModel](context.Background(), dbh, "SELECT id, name FROM test WHERE id > :id", Params{"id": 0})
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+	assert.Equal(t, []scanTestModel{{1, "first"}, {2, "second"}, {3, "third"}}, items)
+	assert.Equal(t, []string{"SELECT id, name FROM test WHERE id > 0"}, s.Log())
+	assert.Equal(t, 0, s.OpenRows())
+
+	_, err = QueryRows[scanTestModel](context.Background(), dbh, "SELECT id, name FROM test WHERE id > :id", nil)
+	assert.Error(t, err)
+}
+
+func TestQueryOne(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if c.Query == "SELECT id, name FROM test WHERE id = 1" {
+			return rowsOf("id,name", []driver.Value{int64(1), "first"}), nil
+		}
+		return rowsOf("id,name"), nil
+	})
+
+	item, err := QueryOne[scanTestModel](context.Background(), dbh, "SELECT id, name FROM test WHERE id = :id", Params{"id": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, scanTestModel{1, "first"}, item)
+
+	item, err = QueryOne[scanTestModel](context.Background(), dbh, "SELECT id, name FROM test WHERE id = :id", Params{"id": 2})
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, scanTestModel{}, item)
+	assert.Equal(t, 0, s.OpenRows())
+}
+
+func TestQueryEach(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name",
+			[]driver.Value{int64(1), "first"},
+			[]driver.Value{int64(2), "second"},
+			[]driver.Value{int64(3), nil},
+		), nil
+	})
+
+	stop := errors.New("stop")
+	var items []scanTestModel
+	err := QueryEach(context.Background(), dbh, "SELECT id, name FROM test", nil, func(item scanTestModel) error {
+		items = append(items, item)
+		if item.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []scanTestModel{{1, "first"}, {2, "second"}}, items)
+	assert.Equal(t, 0, s.OpenRows())
+
+	// NULL name of the third row can't be scanned
+	items = nil
+	err = QueryEach(context.Background(), dbh, "SELECT id, name FROM test", nil, func(item scanTestModel) error {
+		items = append(items, item)
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, 0, s.OpenRows())
+}
+
+func TestQueryExactlyOne(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		switch c.Query {
+		case "SELECT id, name FROM test WHERE code = 'one'":
+			return rowsOf("id,name", []driver.Value{int64(1), "first"}), nil
+		case "SELECT id, name FROM test WHERE code = 'two'":
+			return rowsOf("id,name",
+				[]driver.Value{int64(1), "first"},
+				[]driver.Value{int64(2), "second"},
+			), nil
+		}
+		return rowsOf("id,name"), nil
+	})
+
+	q := "SELECT id, name FROM test WHERE code = :code"
+	item, err := QueryExactlyOne[scanTestModel](context.Background(), dbh, q, Params{"code": "none"})
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, scanTestModel{}, item)
+
+	item, err = QueryExactlyOne[scanTestModel](context.Background(), dbh, q, Params{"code": "one"})
+	assert.NoError(t, err)
+	assert.Equal(t, scanTestModel{1, "first"}, item)
+
+	item, err = QueryExactlyOne[scanTestModel](context.Background(), dbh, q, Params{"code": "two"})
+	assert.True(t, errors.Is(err, ErrMultipleRows), "unexpected error %v", err)
+	assert.Equal(t, scanTestModel{}, item)
+	assert.Equal(t, 0, s.OpenRows())
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isIdentifier reports whether s can be used in query as unquoted identifier
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case (r >= '0' && r <= '9') || r == '$':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func checkIdentifier(s string) error {
+	if !isIdentifier(s) {
+		return fmt.Errorf("invalid identifier %q", s)
+	}
+	return nil
+}
+
+// checkQualifiedIdentifier allows schema qualified names like "public.users"
+func checkQualifiedIdentifier(s string) error {
+	for _, part := range strings.Split(s, ".") {
+		if !isIdentifier(part) {
+			return fmt.Errorf("invalid identifier %q", s)
+		}
+	}
+	return nil
+}
+
+// checkTypeName allows type names like "int", "public.status" or
+// "timestamp with time zone"
+func checkTypeName(s string) error {
+	for _, part := range strings.Split(s, " ") {
+		if err := checkQualifiedIdentifier(part); err != nil {
+			return fmt.Errorf("invalid type name %q", s)
+		}
+	}
+	return nil
+}
+
+// safeIdentifier leaves * and plain, possibly qualified, identifiers as is
+// and quotes anything else as a single identifier
+func safeIdentifier(s string) string {
+	if s == "*" {
+		return s
+	}
+	parts := strings.Split(s, ".")
+	for i, part := range parts {
+		if !isIdentifier(part) && !(part == "*" && i == len(parts)-1) {
+			return QuoteIdentifier(s)
+		}
+	}
+	return s
+}
+
+// QuoteIdentifier wraps s in double quotes doubling embedded ones
+func QuoteIdentifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
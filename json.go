@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// QueryToJSON streams query result into w as a JSON array of objects keyed
+// by column name. NULLs are written as null and []byte values as strings.
+func QueryToJSON(ctx context.Context, db Queryable, q string, params Params, w io.Writer) error {
+	rows, err := Query(ctx, db, q, params)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return wrapError(err, q, params)
+	}
+	keys := make([][]byte, len(cols))
+	for i, col := range cols {
+		if keys[i], err = json.Marshal(col); err != nil {
+			return err
+		}
+	}
+	values := make([]interface{}, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if _, err = io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for n := 0; rows.Next(); n++ {
+		if err = rows.Scan(dest...); err != nil {
+			return wrapError(err, q, params)
+		}
+		buf := make([]byte, 0, 64)
+		if n > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, '{')
+		for i, v := range values {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, keys[i]...)
+			buf = append(buf, ':')
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return wrapError(err, q, params)
+			}
+			buf = append(buf, encoded...)
+		}
+		buf = append(buf, '}')
+		if _, err = w.Write(buf); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return wrapError(err, q, params)
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryToJSON(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name,comment",
+			[]driver.Value{int64(1), []byte("first"), nil},
+			[]driver.Value{int64(2), []byte("second"), "text"},
+		), nil
+	})
+
+	var buf bytes.Buffer
+	err := QueryToJSON(context.Background(), dbh, "SELECT id, name, comment FROM test WHERE id > :id", Params{"id": 0}, &buf)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id": 1, "name": "first", "comment": null},
+		{"id": 2, "name": "second", "comment": "text"}
+	]`, buf.String())
+	assert.Equal(t, []string{"SELECT id, name, comment FROM test WHERE id > 0"}, s.Log())
+}
+
+func TestQueryToJSONEmpty(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id"), nil
+	})
+
+	var buf bytes.Buffer
+	err := QueryToJSON(context.Background(), dbh, "SELECT id FROM test", Params{}, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", buf.String())
+}
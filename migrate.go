@@ -11,59 +11,143 @@ CREATE TABLE IF NOT EXISTS migrations (version INT NOT NULL PRIMARY KEY);
 INSERT INTO migrations VALUES (1);
 `
 
+// MigrateDB is what Migrate needs from the database, it's implemented
+// by *sql.DB and *sql.Conn
+type MigrateDB interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type Migrate struct {
-	db *sql.DB
+	db MigrateDB
 }
 
 type Migration struct {
 	Version int
 	Sql     string
+	// NonTransactional migration runs outside of transaction, which is
+	// required by e.g. CREATE INDEX CONCURRENTLY. Migrations preceding it
+	// are committed before it runs.
+	NonTransactional bool
 }
 
 func NewMigrate(db *sql.DB) *Migrate {
+	return NewMigrateDB(db)
+}
+
+// NewMigrateDB is NewMigrate accepting a pinned connection or a wrapper
+func NewMigrateDB(db MigrateDB) *Migrate {
 	return &Migrate{
 		db: db,
 	}
 }
 
 func (m *Migrate) Run(migrations []Migration) error {
-	latest, err := m.getLatestVersion()
-	if err != nil {
-		return err
-	}
+	ctx := context.Background()
 
-	tx, err := m.db.Begin()
+	latest, err := m.getLatestVersion(ctx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
-
-	ctx := context.Background()
 
-	for _, m := range migrations {
-		if err != nil {
-			break
+	var batch []Migration
+	nonTx := false
+	for _, migration := range migrations {
+		if migration.Version <= latest {
+			continue
 		}
-		if m.Version > latest {
-			_, err = tx.Exec(m.Sql)
-			latest = m.Version
+		if !migration.NonTransactional {
+			batch = append(batch, migration)
+			latest = migration.Version
+			continue
 		}
+		if len(batch) > 0 {
+			if err = m.runTx(ctx, batch, latest); err != nil {
+				return err
+			}
+			batch = nil
+		}
+		if _, err = m.db.ExecContext(ctx, migration.Sql); err != nil {
+			return err
+		}
+		latest = migration.Version
+		if err = setMigrationVersion(ctx, m.db, latest); err != nil {
+			return err
+		}
+		nonTx = true
+	}
+	if len(batch) == 0 && nonTx {
+		return nil
+	}
+	return m.runTx(ctx, batch, latest)
+}
+
+// runTx applies migrations and sets version in a single transaction
+func (m *Migrate) runTx(ctx context.Context, migrations []Migration, version int) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	if err == nil {
-		_, err := Exec(ctx, tx, "UPDATE migrations SET version = :latest", Params{"latest": latest})
-		if err != nil {
+	for _, migration := range migrations {
+		if _, err = tx.ExecContext(ctx, migration.Sql); err != nil {
 			return err
 		}
-		tx.Commit()
 	}
+	if err = setMigrationVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+func setMigrationVersion(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}, version int) error {
+	query, err := qprintf("UPDATE migrations SET version = "+placeholder("latest"), Params{"latest": version})
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, query)
 	return err
 }
 
-func (m *Migrate) getLatestVersion() (int, error) {
+// MarkApplied records version as applied without running its migration,
+// e.g. when it was applied manually. Only the latest version is tracked,
+// so versions below it count as applied too. Versions not above the
+// current one are ignored.
+func (m *Migrate) MarkApplied(version int) error {
+	ctx := context.Background()
+	latest, err := m.getLatestVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version <= latest {
+		return nil
+	}
+	return setMigrationVersion(ctx, m.db, version)
+}
+
+// IsUpToDate reports whether database version equals the highest
+// version of migrations, i.e. Run has nothing to apply
+func (m *Migrate) IsUpToDate(migrations []Migration) (bool, error) {
+	latest, err := m.getLatestVersion(context.Background())
+	if err != nil {
+		return false, err
+	}
+	expected := 0
+	for _, migration := range migrations {
+		if migration.Version > expected {
+			expected = migration.Version
+		}
+	}
+	return latest == expected, nil
+}
+
+func (m *Migrate) getLatestVersion(ctx context.Context) (int, error) {
 	var latest int
-	row := m.db.QueryRow("SELECT version FROM migrations")
+	row := m.db.QueryRowContext(ctx, "SELECT version FROM migrations")
 	err := row.Scan(&latest)
 	if err != nil && err != sql.ErrNoRows && !strings.Contains(err.Error(), "migrations") {
 		return 0, err
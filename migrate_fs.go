@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadMigrations reads migrations from dir of fsys, e.g. embed.FS. Every
+// file is named NNN_description.sql where NNN is the migration version,
+// other files are ignored.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		prefix := strings.TrimSuffix(name, ".sql")
+		if idx := strings.IndexByte(prefix, '_'); idx != -1 {
+			prefix = prefix[:idx]
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s doesn't start with version", name)
+		}
+		if prev, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migration files %s and %s have the same version %d", prev, name, version)
+		}
+		seen[version] = name
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Sql: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// ValidateMigrations checks that migrations are the same as the ones loaded
+// from dir of fsys with LoadMigrations. SQL is compared with whitespace
+// collapsed, all differences are reported in the error.
+func ValidateMigrations(migrations []Migration, fsys fs.FS, dir string) error {
+	loaded, err := LoadMigrations(fsys, dir)
+	if err != nil {
+		return err
+	}
+	files := make(map[int]string, len(loaded))
+	for _, m := range loaded {
+		files[m.Version] = m.Sql
+	}
+
+	var problems []string
+	for _, m := range migrations {
+		sql, ok := files[m.Version]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("migration %d has no file", m.Version))
+			continue
+		}
+		delete(files, m.Version)
+		if normalizeSQL(sql) != normalizeSQL(m.Sql) {
+			problems = append(problems, fmt.Sprintf("migration %d differs from its file", m.Version))
+		}
+	}
+	for _, m := range loaded {
+		if _, ok := files[m.Version]; ok {
+			problems = append(problems, fmt.Sprintf("migration file of version %d has no migration", m.Version))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("migrations drifted: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
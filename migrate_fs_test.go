@@ -0,0 +1,50 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/002_create_test.sql": {Data: []byte("CREATE TABLE test (id INT);")},
+		"migrations/001_init.sql":        {Data: []byte(InitialMigration)},
+		"migrations/README.md":           {Data: []byte("docs")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "migrations")
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Sql: InitialMigration},
+		{Version: 2, Sql: "CREATE TABLE test (id INT);"},
+	}, migrations)
+
+	fsys["migrations/2_duplicate.sql"] = &fstest.MapFile{Data: []byte("SELECT 1")}
+	_, err = LoadMigrations(fsys, "migrations")
+	assert.Error(t, err)
+}
+
+func TestValidateMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001_init.sql":        {Data: []byte(InitialMigration)},
+		"migrations/002_create_test.sql": {Data: []byte("CREATE TABLE test (\n\tid INT\n);\n")},
+	}
+
+	err := ValidateMigrations([]Migration{
+		{Version: 1, Sql: InitialMigration},
+		{Version: 2, Sql: "CREATE TABLE test ( id INT );"},
+	}, fsys, "migrations")
+	assert.NoError(t, err)
+
+	err = ValidateMigrations([]Migration{
+		{Version: 1, Sql: InitialMigration},
+		{Version: 2, Sql: "CREATE TABLE test (id BIGINT);"},
+		{Version: 3, Sql: "DROP TABLE test;"},
+	}, fsys, "migrations")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "migration 2 differs")
+		assert.Contains(t, err.Error(), "migration 3 has no file")
+	}
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingMigrateDB is a MigrateDB wrapper counting calls
+type countingMigrateDB struct {
+	MigrateDB
+	begins, queries int
+}
+
+func (db *countingMigrateDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	db.begins++
+	return db.MigrateDB.BeginTx(ctx, opts)
+}
+
+func (db *countingMigrateDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	db.queries++
+	return db.MigrateDB.QueryRowContext(ctx, query, args...)
+}
+
+func TestMigrateDB(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if c.Query == "SELECT version FROM migrations" {
+			return rowsOf("version", []driver.Value{int64(1)}), nil
+		}
+		return nil, nil
+	})
+	conn, err := dbh.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	mdb := &countingMigrateDB{MigrateDB: conn}
+	err = NewMigrateDB(mdb).Run([]Migration{
+		{Version: 1, Sql: InitialMigration},
+		{Version: 2, Sql: "CREATE TABLE test (id INT)"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mdb.begins)
+	assert.Equal(t, 1, mdb.queries)
+	assert.Equal(t, []string{
+		"SELECT version FROM migrations",
+		"BEGIN",
+		"CREATE TABLE test (id INT)",
+		"UPDATE migrations SET version = 2",
+		"COMMIT",
+	}, s.Log())
+}
+
+func TestMigrateNonTransactional(t *testing.T) {
+	var inTx []bool
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if c.Query == "SELECT version FROM migrations" {
+			return rowsOf("version", []driver.Value{int64(1)}), nil
+		}
+		inTx = append(inTx, c.InTx)
+		return nil, nil
+	})
+
+	err := NewMigrate(dbh).Run([]Migration{
+		{Version: 1, Sql: InitialMigration},
+		{Version: 2, Sql: "CREATE TABLE test (id INT)"},
+		{Version: 3, Sql: "CREATE INDEX CONCURRENTLY test_id ON test (id)", NonTransactional: true},
+		{Version: 4, Sql: "ALTER TABLE test ADD name TEXT"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"SELECT version FROM migrations",
+		"BEGIN",
+		"CREATE TABLE test (id INT)",
+		"UPDATE migrations SET version = 2",
+		"COMMIT",
+		"CREATE INDEX CONCURRENTLY test_id ON test (id)",
+		"UPDATE migrations SET version = 3",
+		"BEGIN",
+		"ALTER TABLE test ADD name TEXT",
+		"UPDATE migrations SET version = 4",
+		"COMMIT",
+	}, s.Log())
+	assert.Equal(t, []bool{true, true, false, false, true, true}, inTx)
+}
+
+func TestMigrateIsUpToDate(t *testing.T) {
+	version := int64(2)
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("version", []driver.Value{version}), nil
+	})
+	migrations := []Migration{
+		{Version: 1, Sql: InitialMigration},
+		{Version: 2, Sql: "CREATE TABLE test (id INT)"},
+	}
+
+	ok, err := NewMigrate(dbh).IsUpToDate(migrations)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	version = 1
+	ok, err = NewMigrate(dbh).IsUpToDate(migrations)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMigrateMarkApplied(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if c.Query == "SELECT version FROM migrations" {
+			return rowsOf("version", []driver.Value{int64(2)}), nil
+		}
+		return nil, nil
+	})
+
+	assert.NoError(t, NewMigrate(dbh).MarkApplied(3))
+	assert.NoError(t, NewMigrate(dbh).MarkApplied(1))
+	assert.Equal(t, []string{
+		"SELECT version FROM migrations",
+		"UPDATE migrations SET version = 3",
+		"SELECT version FROM migrations",
+	}, s.Log())
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+var openBackoff = BackoffConfig{Initial: 100 * time.Millisecond, Max: 5 * time.Second}
+
+// PoolConfig holds connection pool settings applied by Open.
+// Zero values keep the database/sql defaults.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (c PoolConfig) apply(db *sql.DB) {
+	if c.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+}
+
+// Open opens the database and pings it with exponential backoff until it
+// responds or maxWait elapses, so services don't have to wait for the
+// database on startup by themselves
+func Open(ctx context.Context, driverName, dsn string, cfg PoolConfig, maxWait time.Duration) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.apply(db)
+
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return db, nil
+		}
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("database is not ready after %s: %w", maxWait, err)
+		case <-time.After(openBackoff.delay(attempt)):
+		}
+	}
+}
+
+// StartPoolStatsReporter passes db pool stats to report every interval
+// until ctx is done, e.g. to export WaitCount and WaitDuration as metrics.
+// Interval must be positive.
+func StartPoolStatsReporter(ctx context.Context, db *sql.DB, interval time.Duration, report func(sql.DBStats)) error {
+	if interval <= 0 {
+		return fmt.Errorf("pool stats interval must be positive, got %s", interval)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report(db.Stats())
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+	s, dsn := newFakeServer(t, nil)
+	s.pingErrs = 2
+
+	dbh, err := Open(context.Background(), fakeDriverName, dsn, PoolConfig{MaxOpenConns: 3}, 5*time.Second)
+	if assert.NoError(t, err) {
+		defer dbh.Close()
+		assert.Equal(t, 3, s.pings)
+		assert.Equal(t, 3, dbh.Stats().MaxOpenConnections)
+	}
+}
+
+func TestOpenGivesUp(t *testing.T) {
+	s, dsn := newFakeServer(t, nil)
+	s.pingErrs = 1000
+
+	started := time.Now()
+	_, err := Open(context.Background(), fakeDriverName, dsn, PoolConfig{}, 250*time.Millisecond)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(started), 2*time.Second)
+}
+
+func TestStartPoolStatsReporter(t *testing.T) {
+	dbh, _ := newFakeDB(t, nil)
+	dbh.SetMaxOpenConns(3)
+
+	var reports int32
+	stats := make(chan sql.DBStats, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	err := StartPoolStatsReporter(ctx, dbh, time.Millisecond, func(s sql.DBStats) {
+		atomic.AddInt32(&reports, 1)
+		stats <- s
+	})
+	assert.NoError(t, err)
+
+	s := <-stats
+	assert.Equal(t, 3, s.MaxOpenConnections)
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	n := atomic.LoadInt32(&reports)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&reports))
+
+	err = StartPoolStatsReporter(context.Background(), dbh, 0, func(s sql.DBStats) {})
+	assert.Error(t, err)
+}
@@ -0,0 +1,594 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// paramRenderer is implemented by parameter wrappers
+// which know how to render themselves in SQL query
+type paramRenderer interface {
+	renderParam() (string, error)
+}
+
+// PgBoolParam renders bool or *bool as 't'/'f' literal
+// for legacy char-boolean columns, nil renders as NULL
+type PgBoolParam struct {
+	Value interface{}
+}
+
+func (p PgBoolParam) renderParam() (string, error) {
+	switch v := p.Value.(type) {
+	case nil:
+		return "NULL", nil
+	case *bool:
+		if v == nil {
+			return "NULL", nil
+		}
+		return PgBoolParam{*v}.renderParam()
+	case bool:
+		if v {
+			return "'t'", nil
+		}
+		return "'f'", nil
+	}
+	return "", fmt.Errorf("PgBoolParam: unsupported value type %T", p.Value)
+}
+
+// PointParam renders coordinates as Postgres point
+type PointParam struct {
+	X, Y float64
+}
+
+func (p PointParam) renderParam() (string, error) {
+	return "'" + p.coords() + "'::point", nil
+}
+
+func (p PointParam) coords() string {
+	return "(" + formatFloat(p.X) + "," + formatFloat(p.Y) + ")"
+}
+
+// BoxParam renders two opposite corners as Postgres box
+type BoxParam struct {
+	A, B PointParam
+}
+
+func (p BoxParam) renderParam() (string, error) {
+	return "'(" + p.A.coords() + "," + p.B.coords() + ")'::box", nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Renderer converts value to SQL text inserted in query as is
+type Renderer func(value interface{}) (string, error)
+
+var (
+	renderers     = map[reflect.Type]Renderer{}
+	renderersLock sync.RWMutex
+)
+
+// RegisterRenderer makes all values of the same type as sample to be
+// rendered by r. It's intended to be called from package init.
+func RegisterRenderer(sample interface{}, r Renderer) {
+	renderersLock.Lock()
+	defer renderersLock.Unlock()
+	renderers[reflect.TypeOf(sample)] = r
+}
+
+func registeredRenderer(value interface{}) (Renderer, bool) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	r, ok := renderers[reflect.TypeOf(value)]
+	return r, ok
+}
+
+// RenderAs overrides rendering of a single parameter value
+func RenderAs(value interface{}, as Renderer) interface{} {
+	return renderAsParam{value: value, as: as}
+}
+
+type renderAsParam struct {
+	value interface{}
+	as    Renderer
+}
+
+func (p renderAsParam) renderParam() (string, error) {
+	return p.as(p.value)
+}
+
+// MaxLimit is the upper bound LimitParam values are clamped to
+var MaxLimit = 1000
+
+// LimitParam renders untrusted limit as bare integer clamped
+// to MaxLimit, negative values are rejected
+type LimitParam int
+
+func (p LimitParam) renderParam() (string, error) {
+	if p < 0 {
+		return "", fmt.Errorf("negative limit %d", int(p))
+	}
+	if int(p) > MaxLimit {
+		p = LimitParam(MaxLimit)
+	}
+	return strconv.Itoa(int(p)), nil
+}
+
+// EnumParam renders enum label as quoted literal, cast
+// to Type (which may be schema qualified) when it's set
+type EnumParam struct {
+	Label string
+	Type  string
+}
+
+func (p EnumParam) renderParam() (string, error) {
+	if p.Type == "" {
+		return QuoteLiteral(p.Label), nil
+	}
+	if err := checkQualifiedIdentifier(p.Type); err != nil {
+		return "", err
+	}
+	return QuoteLiteral(p.Label) + "::" + p.Type, nil
+}
+
+// ErrorParam renders error message as quoted string, nil error as NULL.
+// Plain error values are rejected as parameters since it's usually a bug.
+type ErrorParam struct {
+	Err error
+}
+
+func (p ErrorParam) renderParam() (string, error) {
+	if p.Err == nil {
+		return "NULL", nil
+	}
+	return QuoteLiteral(p.Err.Error()), nil
+}
+
+// IntervalParam renders calendar interval which time.Duration can't
+// represent, e.g. IntervalParam{Months: 1} is '1 mon'::interval
+type IntervalParam struct {
+	Months  int
+	Days    int
+	Seconds float64
+}
+
+func (p IntervalParam) renderParam() (string, error) {
+	var parts []string
+	if p.Months != 0 {
+		parts = append(parts, strconv.Itoa(p.Months)+" mon")
+	}
+	if p.Days != 0 {
+		parts = append(parts, strconv.Itoa(p.Days)+" days")
+	}
+	if p.Seconds != 0 || len(parts) == 0 {
+		// interval input doesn't accept exponent form
+		parts = append(parts, strconv.FormatFloat(p.Seconds, 'f', -1, 64)+" secs")
+	}
+	return "'" + strings.Join(parts, " ") + "'::interval", nil
+}
+
+// ZonedTimeParam renders time converted to local time of the named zone:
+// ('2006-01-02 15:04:05+00'::timestamptz AT TIME ZONE 'Europe/Moscow')
+type ZonedTimeParam struct {
+	Time time.Time
+	Zone string
+}
+
+func (p ZonedTimeParam) renderParam() (string, error) {
+	if p.Zone == "" {
+		return "", errors.New("ZonedTimeParam: empty time zone")
+	}
+	ts, err := toDbValue(p.Time)
+	if err != nil {
+		return "", err
+	}
+	return "(" + ts + "::timestamptz AT TIME ZONE " + QuoteLiteral(p.Zone) + ")", nil
+}
+
+// ArrayParam renders slice as ARRAY[...] constructor cast to Type[] when Type
+// is set. Type is required for empty arrays since Postgres can't infer it.
+// []byte elements are rendered as hex bytea literals and make Type default
+// to bytea. Nil slice renders as NULL.
+type ArrayParam struct {
+	Values interface{}
+	Type   string
+}
+
+func (p ArrayParam) renderParam() (string, error) {
+	if p.Values == nil {
+		return "NULL", nil
+	}
+	v := reflect.ValueOf(p.Values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("ArrayParam: slice expected; got %T", p.Values)
+	}
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return "NULL", nil
+	}
+	typ := p.Type
+	if typ == "" && v.Type().Elem() == reflect.TypeOf([]byte(nil)) {
+		typ = "bytea"
+	}
+	if typ == "" && v.Len() == 0 {
+		return "", errors.New("ArrayParam: type is required for empty array")
+	}
+	if typ != "" {
+		if err := checkTypeName(typ); err != nil {
+			return "", err
+		}
+	}
+
+	elems := make([]string, v.Len())
+	for i := range elems {
+		var err error
+		if elems[i], err = toDbValue(v.Index(i).Interface()); err != nil {
+			return "", err
+		}
+	}
+	s := "ARRAY[" + strings.Join(elems, ", ") + "]"
+	if typ != "" {
+		s += "::" + typ + "[]"
+	}
+	return s, nil
+}
+
+// AnyArrayParam renders slice as ANY(ARRAY[...]::type[]) for use in
+// "col = :ids" comparisons instead of IN lists. Type is inferred from
+// the element type when not set: integers, floats, bool, string,
+// decimal.Decimal, time.Time and 16-byte arrays (UUIDs) map to the matching
+// Postgres types. Elements of other types are rendered as JSON and compared
+// as jsonb, which is rarely what's meant, so Type should be set for them.
+// Empty or nil slice matches nothing.
+type AnyArrayParam struct {
+	Values interface{}
+	Type   string
+}
+
+// Any is a shortcut for AnyArrayParam with inferred type
+func Any(values interface{}) AnyArrayParam {
+	return AnyArrayParam{Values: values}
+}
+
+func (p AnyArrayParam) renderParam() (string, error) {
+	v := reflect.ValueOf(p.Values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("AnyArrayParam: slice expected; got %T", p.Values)
+	}
+	typ := p.Type
+	if typ == "" {
+		typ = pgArrayElemType(v.Type().Elem())
+	}
+	if v.Len() == 0 {
+		if err := checkTypeName(typ); err != nil {
+			return "", err
+		}
+		return "ANY('{}'::" + typ + "[])", nil
+	}
+	values := p.Values
+	if isUUIDType(v.Type().Elem()) {
+		values = uuidStrings(v)
+	}
+	arr, err := ArrayParam{Values: values, Type: typ}.renderParam()
+	if err != nil {
+		return "", err
+	}
+	return "ANY(" + arr + ")", nil
+}
+
+var (
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	timeType    = reflect.TypeOf(time.Time{})
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// pgArrayElemType returns Postgres type for Go array element type,
+// jsonb when there's no obvious one
+func pgArrayElemType(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == decimalType:
+		return "numeric"
+	case t == timeType:
+		return "timestamptz"
+	case isUUIDType(t):
+		return "uuid"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "bigint"
+	case reflect.Int32:
+		return "integer"
+	case reflect.Int8, reflect.Int16:
+		return "smallint"
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "bigint"
+	// uint and uint64 may not fit bigint
+	case reflect.Uint, reflect.Uint64:
+		return "numeric"
+	case reflect.Float64:
+		return "double precision"
+	case reflect.Float32:
+		return "real"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "text"
+	}
+	return "jsonb"
+}
+
+// isUUIDType reports whether t is 16-byte array like uuid.UUID types
+// of the popular packages
+func isUUIDType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// uuidStrings formats UUIDs of v in canonical form, elements which are
+// driver.Valuer are left to render themselves
+func uuidStrings(v reflect.Value) []interface{} {
+	values := make([]interface{}, v.Len())
+	for i := range values {
+		elem := v.Index(i)
+		if elem.Type().Implements(valuerType) {
+			values[i] = elem.Interface()
+			continue
+		}
+		b := make([]byte, 16)
+		reflect.Copy(reflect.ValueOf(b), elem)
+		h := hex.EncodeToString(b)
+		values[i] = h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	}
+	return values
+}
+
+// byteaLiteral renders bytes in bytea hex format, nil as NULL
+func byteaLiteral(b []byte) string {
+	if b == nil {
+		return "NULL"
+	}
+	return QuoteLiteral(`\x` + hex.EncodeToString(b))
+}
+
+// NumericParam renders decimal.Decimal or *decimal.Decimal with explicit
+// ::numeric cast, nil renders as NULL::numeric
+type NumericParam struct {
+	Value interface{}
+}
+
+func (p NumericParam) renderParam() (string, error) {
+	switch v := p.Value.(type) {
+	case nil:
+		return "NULL::numeric", nil
+	case *decimal.Decimal:
+		if v == nil {
+			return "NULL::numeric", nil
+		}
+		return NumericParam{*v}.renderParam()
+	case decimal.Decimal:
+		return v.String() + "::numeric", nil
+	}
+	return "", fmt.Errorf("NumericParam: unsupported value type %T", p.Value)
+}
+
+// JSONFieldsParam renders Value as JSON object keeping only top-level
+// fields listed in Only (all when empty) and dropping those in Exclude.
+// Fields are named as they appear in JSON, i.e. by json tags.
+type JSONFieldsParam struct {
+	Value   interface{}
+	Only    []string
+	Exclude []string
+}
+
+func (p JSONFieldsParam) renderParam() (string, error) {
+	encoded, err := json.Marshal(p.Value)
+	if err != nil {
+		return "", err
+	}
+	if string(encoded) == "null" {
+		return "NULL", nil
+	}
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(encoded, &fields); err != nil {
+		return "", fmt.Errorf("JSONFieldsParam: %T is not encoded as JSON object", p.Value)
+	}
+	if len(p.Only) > 0 {
+		only := make(map[string]json.RawMessage, len(p.Only))
+		for _, name := range p.Only {
+			if v, ok := fields[name]; ok {
+				only[name] = v
+			}
+		}
+		fields = only
+	}
+	for _, name := range p.Exclude {
+		delete(fields, name)
+	}
+	if encoded, err = json.Marshal(fields); err != nil {
+		return "", err
+	}
+	return QuoteLiteral(string(encoded)), nil
+}
+
+// MoneyParam renders decimal.Decimal or *decimal.Decimal as money literal
+// like '12.34'::money, nil renders as NULL::money
+type MoneyParam struct {
+	Value interface{}
+}
+
+func (p MoneyParam) renderParam() (string, error) {
+	switch v := p.Value.(type) {
+	case nil:
+		return "NULL::money", nil
+	case *decimal.Decimal:
+		if v == nil {
+			return "NULL::money", nil
+		}
+		return MoneyParam{*v}.renderParam()
+	case decimal.Decimal:
+		return QuoteLiteral(v.String()) + "::money", nil
+	}
+	return "", fmt.Errorf("MoneyParam: unsupported value type %T", p.Value)
+}
+
+// TSQueryMode selects function TSQueryParam text is parsed with
+type TSQueryMode int
+
+const (
+	// TSQueryPlain ANDs all words, query syntax is ignored
+	TSQueryPlain TSQueryMode = iota
+	// TSQueryPhrase matches words in the given order
+	TSQueryPhrase
+	// TSQueryWebSearch understands "quoted phrases", OR and -negation
+	// and never fails on invalid syntax
+	TSQueryWebSearch
+	// TSQueryRaw passes text to to_tsquery as is, so it must be valid
+	// tsquery syntax and is not suitable for user input
+	TSQueryRaw
+)
+
+var tsQueryFuncs = map[TSQueryMode]string{
+	TSQueryPlain:     "plainto_tsquery",
+	TSQueryPhrase:    "phraseto_tsquery",
+	TSQueryWebSearch: "websearch_to_tsquery",
+	TSQueryRaw:       "to_tsquery",
+}
+
+// TSQueryParam renders search text as tsquery, e.g.
+// plainto_tsquery('english', 'text'). Config is text search configuration,
+// the default one is used when it's empty.
+type TSQueryParam struct {
+	Text   string
+	Config string
+	Mode   TSQueryMode
+}
+
+func (p TSQueryParam) renderParam() (string, error) {
+	fn, ok := tsQueryFuncs[p.Mode]
+	if !ok {
+		return "", fmt.Errorf("TSQueryParam: unknown mode %d", p.Mode)
+	}
+	if p.Config == "" {
+		return fn + "(" + QuoteLiteral(p.Text) + ")", nil
+	}
+	if err := checkQualifiedIdentifier(p.Config); err != nil {
+		return "", err
+	}
+	return fn + "(" + QuoteLiteral(p.Config) + ", " + QuoteLiteral(p.Text) + ")", nil
+}
+
+// RawParam is trusted SQL expression inserted into query as is, without
+// quoting or escaping. It must never be built from user input: that
+// would allow SQL injection the rest of params protect from.
+type RawParam string
+
+// RawSQL is another name for RawParam
+type RawSQL = RawParam
+
+const (
+	// Now is the current time, i.e. start of the current transaction
+	Now RawParam = "now()"
+	// TransactionNow is the same as Now, spelled the way making it clear
+	// the value is the same for all statements of a transaction
+	TransactionNow RawParam = "transaction_timestamp()"
+)
+
+func (p RawParam) renderParam() (string, error) {
+	return string(p), nil
+}
+
+// EmptyStringAsNull renders string or *string, empty string renders as NULL
+type EmptyStringAsNull struct {
+	Value interface{}
+}
+
+func (p EmptyStringAsNull) renderParam() (string, error) {
+	switch v := p.Value.(type) {
+	case nil:
+		return "NULL", nil
+	case *string:
+		if v == nil {
+			return "NULL", nil
+		}
+		return EmptyStringAsNull{*v}.renderParam()
+	case string:
+		if v == "" {
+			return "NULL", nil
+		}
+		return QuoteLiteral(v), nil
+	}
+	return "", fmt.Errorf("EmptyStringAsNull: unsupported value type %T", p.Value)
+}
+
+// Units of EpochParam
+const (
+	EpochSeconds = "s"
+	EpochMillis  = "ms"
+)
+
+// EpochParam renders time as integer Unix epoch in Unit, which is
+// EpochSeconds (the default when empty) or EpochMillis. Zero time is
+// rendered as NULL when ZeroTimeAsNull is set.
+type EpochParam struct {
+	Time time.Time
+	Unit string
+}
+
+func (p EpochParam) renderParam() (string, error) {
+	if ZeroTimeAsNull && p.Time.IsZero() {
+		return "NULL", nil
+	}
+	switch p.Unit {
+	case "", EpochSeconds:
+		return strconv.FormatInt(p.Time.Unix(), 10), nil
+	case EpochMillis:
+		return strconv.FormatInt(p.Time.UnixMilli(), 10), nil
+	}
+	return "", fmt.Errorf("EpochParam: unknown unit %q", p.Unit)
+}
+
+// Ident renders as always quoted identifier, e.g. Ident("user name") is
+// "user name", so table and column names can come from config. Dots are
+// part of the name, qualified name is a concatenation of Idents.
+type Ident string
+
+func (p Ident) renderParam() (string, error) {
+	if p == "" {
+		return "", errors.New("Ident: empty identifier")
+	}
+	return QuoteIdentifier(string(p)), nil
+}
+
+// JSONPathParam renders path of jsonb keys as text array literal for #> and
+// #>> operators, e.g. JSONPathParam{"a", "b"} is '{"a","b"}'. Keys are
+// always quoted, so they may contain commas, braces and quotes. Nil path
+// renders as NULL.
+type JSONPathParam []string
+
+func (p JSONPathParam) renderParam() (string, error) {
+	if p == nil {
+		return "NULL", nil
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, key := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeArrayString(&b, key)
+	}
+	b.WriteByte('}')
+	return QuoteLiteral(b.String()), nil
+}
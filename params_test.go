@@ -0,0 +1,383 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamWrappers(t *testing.T) {
+	var nilBool *bool
+	trueValue, falseValue := true, false
+	var nilDecimal *decimal.Decimal
+	var nilString *string
+	emptyString := ""
+	jsonUser := struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		PasswordHash string `json:"password_hash"`
+	}{1, "it's", "secret"}
+
+	var cases = []struct {
+		SQL            string
+		params         Params
+		expectedResult string
+	}{
+		// char-boolean from nullable bool
+		{
+			":a, :b, :c",
+			Params{"a": PgBoolParam{nilBool}, "b": PgBoolParam{&trueValue}, "c": PgBoolParam{&falseValue}},
+			"NULL, 't', 'f'",
+		},
+		// char-boolean from plain bool
+		{
+			":a, :b",
+			Params{"a": PgBoolParam{true}, "b": PgBoolParam{false}},
+			"'t', 'f'",
+		},
+		// point
+		{
+			":a, :b",
+			Params{"a": PointParam{X: -12.5, Y: 0.1}, "b": PointParam{X: 1e21, Y: -3}},
+			"'(-12.5,0.1)'::point, '(1e+21,-3)'::point",
+		},
+		// box
+		{
+			":a",
+			Params{"a": BoxParam{PointParam{-1, -2}, PointParam{3.25, 4}}},
+			"'((-1,-2),(3.25,4))'::box",
+		},
+		// limit within and above MaxLimit
+		{
+			"LIMIT :a, LIMIT :b, LIMIT :c",
+			Params{"a": LimitParam(0), "b": LimitParam(20), "c": LimitParam(1000000)},
+			"LIMIT 0, LIMIT 20, LIMIT 1000",
+		},
+		// enum label with and without cast
+		{
+			":a, :b, :c",
+			Params{"a": EnumParam{Label: "active"}, "b": EnumParam{Label: "on hold", Type: "order_status"}, "c": EnumParam{Label: "it's", Type: "shop.status"}},
+			"'active', 'on hold'::order_status, 'it''s'::shop.status",
+		},
+		// error message stored explicitly
+		{
+			":a, :b",
+			Params{"a": ErrorParam{errors.New("can't connect")}, "b": ErrorParam{}},
+			"'can''t connect', NULL",
+		},
+		// calendar intervals
+		{
+			":a, :b, :c, :d, :e, :f",
+			Params{
+				"a": IntervalParam{Months: 1, Days: 2, Seconds: 3.5},
+				"b": IntervalParam{Months: -1, Days: -2},
+				"c": IntervalParam{Seconds: -0.25},
+				"d": IntervalParam{},
+				"e": IntervalParam{Seconds: 0.00001},
+				"f": IntervalParam{Seconds: 1e21},
+			},
+			"'1 mon 2 days 3.5 secs'::interval, '-1 mon -2 days'::interval, '-0.25 secs'::interval, '0 secs'::interval, " +
+				"'0.00001 secs'::interval, '1000000000000000000000 secs'::interval",
+		},
+		// time in a named zone
+		{
+			"date_trunc('day', :a)",
+			Params{"a": ZonedTimeParam{Time: time.Date(2023, 3, 2, 21, 30, 0, 0, time.UTC), Zone: "Europe/Moscow"}},
+			"date_trunc('day', ('2023-03-02 21:30:00+00'::timestamptz AT TIME ZONE 'Europe/Moscow'))",
+		},
+		// arrays
+		{
+			":a, :b, :c, :d",
+			Params{
+				"a": ArrayParam{Values: []int{1, 2}},
+				"b": ArrayParam{Values: []string{"x", "it's"}, Type: "text"},
+				"c": ArrayParam{Values: []int{}, Type: "double precision"},
+				"d": ArrayParam{Values: []string(nil)},
+			},
+			"ARRAY[1, 2], ARRAY['x', 'it''s']::text[], ARRAY[]::double precision[], NULL",
+		},
+		// array of blobs
+		{
+			":a",
+			Params{"a": ArrayParam{Values: [][]byte{{0xde, 0xad}, {0x00, 0x01, 0xff}, nil}}},
+			`ARRAY[E'\\xdead', E'\\x0001ff', NULL]::bytea[]`,
+		},
+		// ANY with inferred and explicit type
+		{
+			"id = :a, name = :b, id = :c, name = :d",
+			Params{
+				"a": Any([]int{1, 2}),
+				"b": Any([]string{"x", "it's"}),
+				"c": Any([]int{}),
+				"d": AnyArrayParam{Values: []string(nil), Type: "varchar"},
+			},
+			"id = ANY(ARRAY[1, 2]::bigint[]), name = ANY(ARRAY['x', 'it''s']::text[]), id = ANY('{}'::bigint[]), name = ANY('{}'::varchar[])",
+		},
+		// numeric with cast
+		{
+			":a, :b, :c",
+			Params{"a": NumericParam{decimal.RequireFromString("-12.50")}, "b": NumericParam{nil}, "c": NumericParam{nilDecimal}},
+			"-12.5::numeric, NULL::numeric, NULL::numeric",
+		},
+		// JSON with filtered fields
+		{
+			":a, :b, :c",
+			Params{
+				"a": JSONFieldsParam{Value: jsonUser, Exclude: []string{"password_hash"}},
+				"b": JSONFieldsParam{Value: jsonUser, Only: []string{"id", "missing"}},
+				"c": JSONFieldsParam{Value: nil},
+			},
+			`'{"id":1,"name":"it''s"}', '{"id":1}', NULL`,
+		},
+		// money
+		{
+			":a, :b, :c",
+			Params{"a": MoneyParam{decimal.RequireFromString("12.34")}, "b": MoneyParam{decimal.RequireFromString("-0.5")}, "c": MoneyParam{nilDecimal}},
+			"'12.34'::money, '-0.5'::money, NULL::money",
+		},
+		// full-text search query
+		{
+			":a, :b, :c, :d",
+			Params{
+				"a": TSQueryParam{Text: `rock & roll's (best) !`},
+				"b": TSQueryParam{Text: "rock roll", Config: "english", Mode: TSQueryPhrase},
+				"c": TSQueryParam{Text: `"rock roll" -jazz`, Config: "pg_catalog.english", Mode: TSQueryWebSearch},
+				"d": TSQueryParam{Text: "rock & !jazz", Config: "simple", Mode: TSQueryRaw},
+			},
+			`plainto_tsquery('rock & roll''s (best) !'), phraseto_tsquery('english', 'rock roll'), ` +
+				`websearch_to_tsquery('pg_catalog.english', '"rock roll" -jazz'), to_tsquery('simple', 'rock & !jazz')`,
+		},
+		// raw expressions
+		{
+			":a, :b, :c",
+			Params{"a": Now, "b": TransactionNow, "c": RawParam("clock_timestamp() - interval '1 day'")},
+			"now(), transaction_timestamp(), clock_timestamp() - interval '1 day'",
+		},
+		{
+			"SET updated_at = :ts, name = :name",
+			Params{"ts": RawSQL("now()"), "name": "now()"},
+			"SET updated_at = now(), name = 'now()'",
+		},
+		// decimal array keeps exact values
+		{
+			":a",
+			Params{"a": ArrayParam{Values: []decimal.Decimal{decimal.RequireFromString("1.10"), decimal.RequireFromString("-0.000000000000000001")}, Type: "numeric"}},
+			"ARRAY[1.1, -0.000000000000000001]::numeric[]",
+		},
+		// empty string as NULL
+		{
+			":a, :b, :c, :d, :e",
+			Params{"a": EmptyStringAsNull{""}, "b": EmptyStringAsNull{"it's"}, "c": EmptyStringAsNull{&emptyString}, "d": EmptyStringAsNull{nilString}, "e": ""},
+			"NULL, 'it''s', NULL, NULL, ''",
+		},
+		// epoch
+		{
+			":a, :b, :c",
+			Params{
+				"a": EpochParam{Time: time.Date(2023, 3, 2, 10, 30, 0, 500000000, time.UTC)},
+				"b": EpochParam{Time: time.Date(2023, 3, 2, 10, 30, 0, 500000000, time.UTC), Unit: EpochMillis},
+				"c": EpochParam{Time: time.Unix(-1, 0), Unit: EpochSeconds},
+			},
+			"1677753000, 1677753000500, -1",
+		},
+		// ANY with types inferred from elements
+		{
+			":a, :b, :c, :d, :e, :f",
+			Params{
+				"a": Any([]int32{1}),
+				"b": Any([]decimal.Decimal{decimal.RequireFromString("1.5")}),
+				"c": Any([]time.Time{time.Date(2023, 3, 2, 10, 30, 0, 0, time.UTC)}),
+				"d": Any([]testUUID{{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}}),
+				"e": Any([]testValuerUUID{{0xff}}),
+				"f": Any([]testStruct{{IntValue: 1}}),
+			},
+			"ANY(ARRAY[1]::integer[]), ANY(ARRAY[1.5]::numeric[]), ANY(ARRAY['2023-03-02 10:30:00+00']::timestamptz[]), " +
+				"ANY(ARRAY['123e4567-e89b-12d3-a456-426614174000']::uuid[]), ANY(ARRAY['ff000000-0000-0000-0000-000000000000']::uuid[]), " +
+				`ANY(ARRAY['{"int_value":1}']::jsonb[])`,
+		},
+		// ANY of unsigned integers
+		{
+			":a, :b, :c, :d",
+			Params{
+				"a": Any([]uint{1, 2}),
+				"b": Any([]uint16{3}),
+				"c": Any([]uint64{math.MaxUint64}),
+				"d": Any([]uint{math.MaxUint}),
+			},
+			"ANY(ARRAY[1, 2]::numeric[]), ANY(ARRAY[3]::bigint[]), ANY(ARRAY[18446744073709551615]::numeric[]), " +
+				"ANY(ARRAY[" + strconv.FormatUint(math.MaxUint, 10) + "]::numeric[])",
+		},
+		// quoted identifiers
+		{
+			"SELECT :a, :b FROM :schema.:table",
+			Params{"a": Ident("id"), "b": Ident("user name"), "schema": Ident("public"), "table": Ident(`say "hi"`)},
+			`SELECT "id", "user name" FROM "public"."say ""hi"""`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			result, err := qprintf(c.SQL, c.params)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectedResult, result)
+		})
+	}
+}
+
+func TestParamWrappersErrors(t *testing.T) {
+	var cases = []struct {
+		SQL    string
+		params Params
+	}{
+		{
+			":a",
+			Params{"a": PgBoolParam{1}},
+		},
+		{
+			"LIMIT :a",
+			Params{"a": LimitParam(-1)},
+		},
+		{
+			":a",
+			Params{"a": EnumParam{Label: "active", Type: "status; DROP TABLE users"}},
+		},
+		// plain error value
+		{
+			":a",
+			Params{"a": errors.New("can't connect")},
+		},
+		{
+			":a",
+			Params{"a": ZonedTimeParam{Time: time.Now()}},
+		},
+		{
+			":a",
+			Params{"a": ArrayParam{Values: []int{}}},
+		},
+		{
+			":a",
+			Params{"a": ArrayParam{Values: []int{1}, Type: "int[]; DROP TABLE users; --"}},
+		},
+		{
+			":a",
+			Params{"a": ArrayParam{Values: 1}},
+		},
+		{
+			":a",
+			Params{"a": NumericParam{1.5}},
+		},
+		{
+			":a",
+			Params{"a": MoneyParam{"12.34"}},
+		},
+		{
+			":a",
+			Params{"a": EmptyStringAsNull{1}},
+		},
+		{
+			"SELECT * FROM :a",
+			Params{"a": Ident("")},
+		},
+		{
+			":a",
+			Params{"a": EpochParam{Time: time.Now(), Unit: "us"}},
+		},
+		{
+			":a",
+			Params{"a": TSQueryParam{Text: "rock", Config: "english'); DROP TABLE users; --"}},
+		},
+		{
+			":a",
+			Params{"a": TSQueryParam{Text: "rock", Mode: TSQueryMode(42)}},
+		},
+		{
+			":a",
+			Params{"a": JSONFieldsParam{Value: []int{1}}},
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			_, err := qprintf(c.SQL, c.params)
+			assert.Error(t, err)
+		})
+	}
+}
+
+type testCurrency struct {
+	Code string
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	RegisterRenderer(testCurrency{}, func(value interface{}) (string, error) {
+		return QuoteLiteral(value.(testCurrency).Code) + "::currency", nil
+	})
+	defer func() {
+		renderersLock.Lock()
+		delete(renderers, reflect.TypeOf(testCurrency{}))
+		renderersLock.Unlock()
+	}()
+
+	result, err := qprintf(":a, :b", Params{"a": testCurrency{"RUB"}, "b": &testCurrency{"USD"}})
+	assert.NoError(t, err)
+	// pointer is a different type and keeps default JSON rendering
+	assert.Equal(t, `'RUB'::currency, '{"Code":"USD"}'`, result)
+}
+
+func TestRenderAs(t *testing.T) {
+	asArray := func(value interface{}) (string, error) {
+		items := value.([]int)
+		elems := make([]string, len(items))
+		for i, item := range items {
+			elems[i] = strconv.Itoa(item)
+		}
+		return "ARRAY[" + strings.Join(elems, ",") + "]", nil
+	}
+
+	result, err := qprintf(":a, :b", Params{"a": RenderAs([]int{1, 2}, asArray), "b": []int{1, 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, "ARRAY[1,2], '[1,2]'", result)
+}
+
+func TestEpochParamZeroTime(t *testing.T) {
+	result, err := qprintf(":a", Params{"a": EpochParam{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "-62135596800", result)
+
+	ZeroTimeAsNull = true
+	defer func() { ZeroTimeAsNull = false }()
+	result, err = qprintf(":a", Params{"a": EpochParam{Unit: EpochMillis}})
+	assert.NoError(t, err)
+	assert.Equal(t, "NULL", result)
+}
+
+type testUUID [16]byte
+
+type testValuerUUID [16]byte
+
+func (u testValuerUUID) Value() (driver.Value, error) {
+	return fmt.Sprintf("%x-0000-0000-0000-000000000000", u[:4]), nil
+}
+
+func TestJSONPathParam(t *testing.T) {
+	result, err := qprintf("data #>> :path", Params{"path": JSONPathParam{"a", "b", "c"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `data #>> '{"a","b","c"}'`, result)
+
+	result, err = qprintf("data #> :path", Params{"path": JSONPathParam{"a,b", "{c}", `d"e`, "it's", `f\g`, ""}})
+	assert.NoError(t, err)
+	assert.Equal(t, `data #> E'{"a,b","{c}","d\\"e","it''s","f\\\\g",""}'`, result)
+
+	result, err = qprintf("data #> :path", Params{"path": JSONPathParam(nil)})
+	assert.NoError(t, err)
+	assert.Equal(t, "data #> NULL", result)
+}
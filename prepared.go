@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// positional converts :name placeholders into positional $1, $2, ...
+// returning param names by position. Repeated names share the position.
+func positional(sql string) (string, []string, error) {
+	var names []string
+	positions := make(map[string]int)
+	query, err := replacePlaceholders(sql, func(param string) (string, error) {
+		param = normalizeParamName(param)
+		pos, ok := positions[param]
+		if !ok {
+			names = append(names, param)
+			pos = len(names)
+			positions[param] = pos
+		}
+		return "$" + strconv.Itoa(pos), nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return query, names, nil
+}
+
+// PreparedQuery prepares q once and returns function running it with given
+// params and scanning rows into T, and function closing the statement.
+// Params are bound as driver arguments, not rendered into query text, so they
+// must be values the driver understands. The function uses ctx given here.
+func PreparedQuery[T any](ctx context.Context, db Queryable, q string, opts ...ScanOption) (func(params Params) ([]T, error), func() error, error) {
+	query, names, err := positional(q)
+	if err != nil {
+		return nil, nil, wrapError(err, q, nil)
+	}
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, wrapError(err, q, nil)
+	}
+	cfg := newScanConfig(opts)
+
+	run := func(params Params) ([]T, error) {
+		params, err := normalizeParams(params)
+		if err != nil {
+			return nil, wrapError(err, q, params)
+		}
+		args := make([]interface{}, len(names))
+		for i, name := range names {
+			v, ok := params[name]
+			if !ok {
+				return nil, wrapError(fmt.Errorf("parameter %s is missing", name), q, params)
+			}
+			args[i] = v
+		}
+		logQuery(q, params)
+		rows, err := stmt.QueryContext(ctx, args...)
+		if err != nil {
+			return nil, wrapError(err, q, params)
+		}
+		defer rows.Close()
+		var items []T
+		for rows.Next() {
+			var item T
+			if err = scanStruct(rows, &item, cfg); err != nil {
+				return nil, wrapError(err, q, params)
+			}
+			items = append(items, item)
+		}
+		if err = rows.Err(); err != nil {
+			return nil, wrapError(err, q, params)
+		}
+		return items, nil
+	}
+	return run, stmt.Close, nil
+}
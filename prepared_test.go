@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPositional(t *testing.T) {
+	query, names, err := positional("SELECT :a, :b::text, :a, '1'::int")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT $1, $2::text, $1, '1'::int", query)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	query, names, err = positional("SELECT * -- user's rows\nFROM t WHERE id = :id")
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * -- user's rows\nFROM t WHERE id = $1", query)
+	assert.Equal(t, []string{"id"}, names)
+}
+
+func TestPreparedQuery(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		id := c.Args[0].Value.(int64)
+		return rowsOf("id,name", []driver.Value{id, fmt.Sprintf("name %d", id)}), nil
+	})
+
+	run, closeFn, err := PreparedQuery[scanTestModel](context.Background(), dbh, "SELECT id, name FROM test WHERE id = :id")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for id := 1; id <= 3; id++ {
+		items, err := run(Params{"id": id})
+		assert.NoError(t, err)
+		assert.Equal(t, []scanTestModel{{int64(id), fmt.Sprintf("name %d", id)}}, items)
+	}
+	_, err = run(Params{})
+	assert.Error(t, err)
+	assert.NoError(t, closeFn())
+
+	assert.Equal(t, 1, s.prepares)
+	assert.Equal(t, []string{
+		"SELECT id, name FROM test WHERE id = $1",
+		"SELECT id, name FROM test WHERE id = $1",
+		"SELECT id, name FROM test WHERE id = $1",
+	}, s.Log())
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+type recordingQueryable struct {
+	db      Queryable
+	mu      sync.Mutex
+	queries *[]string
+}
+
+// RecordingQueryable wraps q so that every query it gets, i.e. SQL already
+// rendered from params, is appended to the returned slice. It's meant for
+// transcript tests of the code building queries.
+func RecordingQueryable(q Queryable) (Queryable, *[]string) {
+	queries := []string{}
+	return &recordingQueryable{db: q, queries: &queries}, &queries
+}
+
+func (r *recordingQueryable) record(query string) {
+	r.mu.Lock()
+	*r.queries = append(*r.queries, query)
+	r.mu.Unlock()
+}
+
+func (r *recordingQueryable) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.record(query)
+	return r.db.ExecContext(ctx, query, args...)
+}
+
+func (r *recordingQueryable) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	r.record(query)
+	return r.db.PrepareContext(ctx, query)
+}
+
+func (r *recordingQueryable) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.record(query)
+	return r.db.QueryContext(ctx, query, args...)
+}
+
+func (r *recordingQueryable) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	r.record(query)
+	return r.db.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingQueryable(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id", []driver.Value{int64(7)}), nil
+	})
+	q, queries := RecordingQueryable(dbh)
+	ctx := context.Background()
+
+	var id int
+	err := QueryRowAndScan(ctx, q, "SELECT id FROM test WHERE name = :name", Params{"name": "it's"}, &id)
+	assert.NoError(t, err)
+	_, err = Exec(ctx, q, "UPDATE test SET seen = :seen WHERE id = :id", Params{"id": id, "seen": true})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"SELECT id FROM test WHERE name = 'it''s'",
+		"UPDATE test SET seen = true WHERE id = 7",
+	}, *queries)
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type resilientQueryable struct {
+	db       *sql.DB
+	maxRetry int
+}
+
+// ResilientQueryable wraps db so that read queries failing with a connection
+// error (see ClassConnection), e.g. because server-side connections were reset
+// by failover, are retried up to maxRetry times. Connections reported broken
+// by the driver are discarded by database/sql, so the retry gets another one.
+//
+// Only QueryContext and QueryRowContext are retried and they must be used for
+// idempotent statements only: the server may have executed the statement
+// before the connection broke. ExecContext and PrepareContext are not retried.
+func ResilientQueryable(db *sql.DB, maxRetry int) Queryable {
+	return &resilientQueryable{db: db, maxRetry: maxRetry}
+}
+
+func (r *resilientQueryable) shouldRetry(ctx context.Context, attempt int, err error) bool {
+	return attempt < r.maxRetry && ctx.Err() == nil && Classify(err) == ClassConnection
+}
+
+func (r *resilientQueryable) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.db.ExecContext(ctx, query, args...)
+}
+
+func (r *resilientQueryable) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.db.PrepareContext(ctx, query)
+}
+
+func (r *resilientQueryable) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	for attempt := 0; ; attempt++ {
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err == nil || !r.shouldRetry(ctx, attempt, err) {
+			return rows, err
+		}
+	}
+}
+
+func (r *resilientQueryable) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	for attempt := 0; ; attempt++ {
+		row := r.db.QueryRowContext(ctx, query, args...)
+		if err := row.Err(); err == nil || !r.shouldRetry(ctx, attempt, err) {
+			return row
+		}
+	}
+}
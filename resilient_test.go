@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResilientQueryable(t *testing.T) {
+	failures := 2
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if failures > 0 {
+			failures--
+			return nil, &pgxError{Code: "08006", Message: "connection reset by failover"}
+		}
+		return rowsOf("id", []driver.Value{int64(7)}), nil
+	})
+
+	var id int
+	err := QueryRowAndScan(context.Background(), ResilientQueryable(dbh, 2), "SELECT id FROM test", nil, &id)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	assert.Len(t, s.Log(), 3)
+
+	failures = 1
+	rows, err := Query(context.Background(), ResilientQueryable(dbh, 1), "SELECT id FROM test", nil)
+	if assert.NoError(t, err) {
+		rows.Close()
+	}
+}
+
+func TestResilientQueryableGivesUp(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return nil, &pgxError{Code: "08006", Message: "connection failure"}
+	})
+
+	_, err := Query(context.Background(), ResilientQueryable(dbh, 2), "SELECT id FROM test", nil)
+	assert.Equal(t, ClassConnection, Classify(err))
+	assert.Len(t, s.Log(), 3)
+}
+
+func TestResilientQueryableDoesNotRetryOtherErrors(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return nil, &pgxError{Code: "42P01", Message: "relation does not exist"}
+	})
+
+	_, err := Query(context.Background(), ResilientQueryable(dbh, 2), "SELECT id FROM test", nil)
+	assert.Error(t, err)
+	assert.Len(t, s.Log(), 1)
+
+	_, err = Exec(context.Background(), ResilientQueryable(dbh, 2), "DELETE FROM test", nil)
+	assert.Error(t, err)
+	assert.Len(t, s.Log(), 2)
+}
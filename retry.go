@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffConfig describes exponential backoff: delay starts at Initial and
+// doubles after every attempt up to Max. Jitter from 0 to 1 is a fraction of
+// the delay randomly cut off it, so clients retrying after the same failure
+// don't do it all at once.
+type BackoffConfig struct {
+	Initial time.Duration
+	Max     time.Duration
+	Jitter  float64
+}
+
+// DefaultBackoff is backoff suitable for retrying conflicting transactions
+var DefaultBackoff = BackoffConfig{Initial: 10 * time.Millisecond, Max: time.Second, Jitter: 0.5}
+
+// delay returns delay before retry after attempt, attempts start with 0.
+// Without Max doubling stops before it overflows.
+func (c BackoffConfig) delay(attempt int) time.Duration {
+	d := c.Initial
+	for i := 0; i < attempt && (c.Max <= 0 || d < c.Max) && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if c.Max > 0 && d > c.Max {
+		d = c.Max
+	}
+	if c.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * c.Jitter * float64(d))
+	}
+	return d
+}
+
+// Retry calls fn up to maxAttempts times while it fails with deadlock or
+// serialization failure (see Classify), waiting with backoff in between.
+// fn must be safe to run again, e.g. run its own transactions with WithTx.
+// Retry stops with ctx error when ctx is done while waiting.
+func Retry(ctx context.Context, maxAttempts int, backoff BackoffConfig, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt+1 >= maxAttempts {
+			return err
+		}
+		if class := Classify(err); class != ClassDeadlock && class != ClassSerialization {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff.delay(attempt)):
+		}
+	}
+}
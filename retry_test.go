@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	backoff := BackoffConfig{Initial: time.Millisecond, Max: 5 * time.Millisecond, Jitter: 0.5}
+
+	calls := 0
+	err := Retry(context.Background(), 3, backoff, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return &pgxError{Code: "40P01", Message: "deadlock detected"}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	err = Retry(context.Background(), 3, backoff, func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("transfer: %w", &pgxError{Code: "40001", Message: "could not serialize access"})
+	})
+	assert.Equal(t, ClassSerialization, Classify(err))
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	failure := errors.New("failure")
+	err = Retry(context.Background(), 3, backoff, func(ctx context.Context) error {
+		calls++
+		return failure
+	})
+	assert.Equal(t, failure, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := Retry(ctx, 3, BackoffConfig{Initial: time.Hour}, func(ctx context.Context) error {
+		cancel()
+		return &pgxError{Code: "40P01", Message: "deadlock detected"}
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBackoffDelay(t *testing.T) {
+	c := BackoffConfig{Initial: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, c.delay(0))
+	assert.Equal(t, 40*time.Millisecond, c.delay(2))
+	assert.Equal(t, 50*time.Millisecond, c.delay(10))
+	assert.Equal(t, 50*time.Millisecond, c.delay(1000))
+
+	unbounded := BackoffConfig{Initial: 10 * time.Millisecond}
+	assert.Equal(t, 80*time.Millisecond, unbounded.delay(3))
+	for _, attempt := range []int{60, 64, 100, 1000} {
+		assert.True(t, unbounded.delay(attempt) >= unbounded.delay(attempt-1), "attempt %d", attempt)
+		assert.True(t, unbounded.delay(attempt) > math.MaxInt64/4, "attempt %d", attempt)
+	}
+
+	c.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := c.delay(1)
+		assert.True(t, d > 10*time.Millisecond && d <= 20*time.Millisecond, "delay %s", d)
+	}
+}
@@ -0,0 +1,349 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kisielk/sqlstruct"
+)
+
+// ScanOption changes the way rows are scanned into structs
+type ScanOption func(*scanConfig)
+
+type scanConfig struct {
+	disallowUnknownColumns bool
+	requireAllFields       bool
+	// nullString is set to string fields scanned from NULL
+	nullString *string
+	// columns scanned into given destinations instead of struct fields
+	columnDests map[string]interface{}
+}
+
+func newScanConfig(opts []ScanOption) *scanConfig {
+	cfg := &scanConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// DisallowUnknownColumns makes scan fail when result set has columns which
+// are not mapped to any struct field. By default such columns are ignored.
+func DisallowUnknownColumns() ScanOption {
+	return func(cfg *scanConfig) {
+		cfg.disallowUnknownColumns = true
+	}
+}
+
+// RequireAllFields makes scan fail when some of struct fields have no
+// matching column in result set, which catches typos in tags and SELECT lists
+func RequireAllFields() ScanOption {
+	return func(cfg *scanConfig) {
+		cfg.requireAllFields = true
+	}
+}
+
+// NullString makes NULL columns scanned into string fields set them
+// to sentinel. By default such scan fails.
+func NullString(sentinel string) ScanOption {
+	return func(cfg *scanConfig) {
+		cfg.nullString = &sentinel
+	}
+}
+
+// structField describes struct field mapped to a column. Fields are mapped
+// the same way sqlstruct does it: by "sql" tag or by field name, either passed
+// through sqlstruct.NameMapper, tag may carry comma separated options after the name.
+type structField struct {
+	index     []int
+	name      string
+	fieldName string
+	options   map[string]string
+}
+
+// structInfo is the mapping of columns to fields of a struct type.
+// Field tagged with "extra" option, e.g. `sql:",extra"`, must be
+// map[string]interface{} and receives all unmapped columns.
+type structInfo struct {
+	fields map[string]*structField
+	// columns are mapped fields in declaration order
+	columns []*structField
+	extra   *structField
+}
+
+func (info *structInfo) add(field *structField) {
+	if _, ok := info.fields[field.name]; ok {
+		for i, f := range info.columns {
+			if f.name == field.name {
+				info.columns[i] = field
+			}
+		}
+	} else {
+		info.columns = append(info.columns, field)
+	}
+	info.fields[field.name] = field
+}
+
+var (
+	structInfoCache = map[reflect.Type]*structInfo{}
+	structInfoLock  sync.RWMutex
+)
+
+func getStructInfo(typ reflect.Type) *structInfo {
+	structInfoLock.RLock()
+	info, ok := structInfoCache[typ]
+	structInfoLock.RUnlock()
+	if ok {
+		return info
+	}
+
+	info = &structInfo{fields: make(map[string]*structField)}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get(sqlstruct.TagName)
+		// skip unexported fields or fields marked with "-"
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		// handle embedded structs
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			embeddedInfo := getStructInfo(f.Type)
+			for _, embedded := range embeddedInfo.columns {
+				info.add(embedded.embeddedAt(i))
+			}
+			if embeddedInfo.extra != nil && info.extra == nil {
+				info.extra = embeddedInfo.extra.embeddedAt(i)
+			}
+			continue
+		}
+		name, options := parseTag(tag)
+		if name == "" {
+			name = f.Name
+		}
+		name = strings.ToLower(sqlstruct.NameMapper(name))
+		field := &structField{
+			index:     []int{i},
+			name:      name,
+			fieldName: f.Name,
+			options:   options,
+		}
+		if _, ok := options["extra"]; ok {
+			info.extra = field
+			continue
+		}
+		info.add(field)
+	}
+
+	structInfoLock.Lock()
+	structInfoCache[typ] = info
+	structInfoLock.Unlock()
+
+	return info
+}
+
+func (f *structField) embeddedAt(i int) *structField {
+	field := *f
+	field.index = append([]int{i}, f.index...)
+	return &field
+}
+
+// parseTag splits tag like "name,opt,key=value" into name and options
+func parseTag(tag string) (string, map[string]string) {
+	parts := strings.Split(tag, ",")
+	var options map[string]string
+	for _, opt := range parts[1:] {
+		if options == nil {
+			options = make(map[string]string)
+		}
+		key, value := opt, ""
+		if idx := strings.IndexByte(opt, '='); idx != -1 {
+			key, value = opt[:idx], opt[idx+1:]
+		}
+		options[key] = value
+	}
+	return parts[0], options
+}
+
+// ScanStruct scans the current row into a struct pointed to by dest.
+// Integer values are range checked by database/sql against the field type,
+// so bigint value which doesn't fit into int32 field (or int field on 32-bit
+// platforms) fails the scan instead of silently wrapping.
+func ScanStruct(rows *sql.Rows, dest interface{}, opts ...ScanOption) error {
+	return scanStruct(rows, dest, newScanConfig(opts))
+}
+
+func scanStruct(rows *sql.Rows, dest interface{}, cfg *scanConfig) error {
+	destv := reflect.ValueOf(dest)
+	if destv.Kind() != reflect.Ptr || destv.IsNil() || destv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dest must be pointer to struct; got %T", dest)
+	}
+	elem := destv.Elem()
+	info := getStructInfo(elem.Type())
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(cols))
+	bound := make(map[string]bool, len(cols))
+	var unknown []string
+	var extra map[string]*interface{}
+	// conversions of scanned values to be done after scan
+	var converts []func() error
+	for i, col := range cols {
+		if d, ok := cfg.columnDests[col]; ok {
+			values[i] = d
+			continue
+		}
+		f, ok := info.fields[strings.ToLower(col)]
+		if !ok {
+			if info.extra != nil {
+				if extra == nil {
+					extra = make(map[string]*interface{})
+				}
+				extra[col] = new(interface{})
+				values[i] = extra[col]
+				continue
+			}
+			// there is no field mapped to this column so we discard it
+			unknown = append(unknown, col)
+			values[i] = &sql.RawBytes{}
+			continue
+		}
+		bound[f.name] = true
+		var convert func() error
+		values[i], convert = fieldDest(elem.FieldByIndex(f.index), f, cfg)
+		if convert != nil {
+			converts = append(converts, convert)
+		}
+	}
+	if cfg.disallowUnknownColumns && len(unknown) > 0 {
+		return fmt.Errorf("columns %s are not mapped to fields of %s", strings.Join(unknown, ", "), elem.Type())
+	}
+	if cfg.requireAllFields {
+		var missing []string
+		for name, f := range info.fields {
+			if !bound[name] {
+				missing = append(missing, f.fieldName)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return fmt.Errorf("fields %s of %s have no matching columns", strings.Join(missing, ", "), elem.Type())
+		}
+	}
+
+	if err = rows.Scan(values...); err != nil {
+		return err
+	}
+	for _, convert := range converts {
+		if err = convert(); err != nil {
+			return err
+		}
+	}
+	if info.extra != nil {
+		return setExtraColumns(elem.FieldByIndex(info.extra.index), extra)
+	}
+	return nil
+}
+
+// fieldDest returns scan destination for the field along with conversion
+// to be done after scan when the field can't be scanned into directly
+func fieldDest(field reflect.Value, f *structField, cfg *scanConfig) (interface{}, func() error) {
+	if layout, ok := f.options["layout"]; ok {
+		var s sql.NullString
+		return &s, func() error {
+			return setTimeFromLayout(field, f, s, layout)
+		}
+	}
+	if _, ok := f.options["json"]; ok {
+		var data []byte
+		return &data, func() error {
+			return setFromJSON(field, f, data)
+		}
+	}
+	if cfg.nullString != nil && field.Kind() == reflect.String {
+		var s sql.NullString
+		return &s, func() error {
+			if s.Valid {
+				field.SetString(s.String)
+			} else {
+				field.SetString(*cfg.nullString)
+			}
+			return nil
+		}
+	}
+	return field.Addr().Interface(), nil
+}
+
+// setFromJSON unmarshals json column into field tagged like
+// `sql:"children,json"`, NULL resets the field to zero value
+func setFromJSON(field reflect.Value, f *structField, data []byte) error {
+	if data == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if err := json.Unmarshal(data, field.Addr().Interface()); err != nil {
+		return fmt.Errorf("column %s: %w", f.name, err)
+	}
+	return nil
+}
+
+// setTimeFromLayout parses string column into time.Time or *time.Time field,
+// used for fields tagged like `sql:"date,layout=2006-01-02"`
+func setTimeFromLayout(field reflect.Value, f *structField, s sql.NullString, layout string) error {
+	switch dest := field.Addr().Interface().(type) {
+	case *time.Time:
+		if !s.Valid {
+			*dest = time.Time{}
+			return nil
+		}
+		t, err := time.Parse(layout, s.String)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", f.name, err)
+		}
+		*dest = t
+	case **time.Time:
+		if !s.Valid {
+			*dest = nil
+			return nil
+		}
+		t, err := time.Parse(layout, s.String)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", f.name, err)
+		}
+		*dest = &t
+	default:
+		return fmt.Errorf("field %s with layout must be time.Time; got %s", f.fieldName, field.Type())
+	}
+	return nil
+}
+
+// setExtraColumns stores scanned unmapped columns into extra field,
+// []byte values are converted to strings
+func setExtraColumns(field reflect.Value, extra map[string]*interface{}) error {
+	m, ok := field.Addr().Interface().(*map[string]interface{})
+	if !ok {
+		return fmt.Errorf("extra field must be map[string]interface{}; got %s", field.Type())
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	if *m == nil {
+		*m = make(map[string]interface{}, len(extra))
+	}
+	for col, v := range extra {
+		if b, ok := (*v).([]byte); ok {
+			*v = string(b)
+		}
+		(*m)[col] = *v
+	}
+	return nil
+}
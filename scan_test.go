@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kisielk/sqlstruct"
+	"github.com/stretchr/testify/assert"
+)
+
+type scanTestModel struct {
+	ID   int64  `sql:"id"`
+	Name string `sql:"name"`
+}
+
+func TestScanIgnoresUnknownColumns(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,debug_info,name", []driver.Value{int64(1), []byte("plan"), "first"}), nil
+	})
+
+	var m scanTestModel
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT id, debug_info, name FROM test", nil, &m)
+	assert.NoError(t, err)
+	assert.Equal(t, scanTestModel{ID: 1, Name: "first"}, m)
+
+	items, err := QueryRowsIntoSlice(context.Background(), dbh, "SELECT id, debug_info, name FROM test", nil, scanTestModel{})
+	assert.NoError(t, err)
+	assert.Equal(t, []scanTestModel{{ID: 1, Name: "first"}}, items)
+}
+
+func TestScanDisallowUnknownColumns(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,debug_info,name", []driver.Value{int64(1), []byte("plan"), "first"}), nil
+	})
+
+	var m scanTestModel
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT id, debug_info, name FROM test", nil, &m, DisallowUnknownColumns())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "debug_info")
+	}
+}
+
+func TestScanStructRejectsNonStruct(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id", []driver.Value{int64(1)}), nil
+	})
+
+	var id int64
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT id FROM test", nil, &id)
+	assert.Error(t, err)
+}
+
+func TestScanRequireAllFields(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,nmae", []driver.Value{int64(1), "first"}), nil
+	})
+
+	var m scanTestModel
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT id, nmae FROM test", nil, &m, RequireAllFields())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Name")
+		assert.NotContains(t, err.Error(), "ID")
+	}
+
+	dbh, _ = newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name", []driver.Value{int64(1), "first"}), nil
+	})
+	err = QueryRowIntoStruct(context.Background(), dbh, "SELECT id, name FROM test", nil, &m, RequireAllFields())
+	assert.NoError(t, err)
+	assert.Equal(t, scanTestModel{ID: 1, Name: "first"}, m)
+}
+
+func TestScanExtraColumns(t *testing.T) {
+	type model struct {
+		ID    int64                  `sql:"id"`
+		Extra map[string]interface{} `sql:",extra"`
+	}
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,color,weight", []driver.Value{int64(1), []byte("red"), nil}), nil
+	})
+
+	var m model
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT * FROM test", nil, &m, DisallowUnknownColumns(), RequireAllFields())
+	assert.NoError(t, err)
+	assert.Equal(t, model{ID: 1, Extra: map[string]interface{}{"color": "red", "weight": nil}}, m)
+}
+
+func TestScanTimeLayout(t *testing.T) {
+	type model struct {
+		Day    time.Time  `sql:"day,layout=2006-01-02"`
+		Closed *time.Time `sql:"closed,layout=02.01.2006 15:04"`
+	}
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("day,closed",
+			[]driver.Value{[]byte("2023-03-02"), "05.03.2023 18:30"},
+			[]driver.Value{"2023-03-03", nil},
+		), nil
+	})
+
+	items, err := QueryRowsIntoSlice(context.Background(), dbh, "SELECT to_char(day, 'YYYY-MM-DD') AS day, closed FROM test", nil, model{})
+	assert.NoError(t, err)
+	closed := time.Date(2023, 3, 5, 18, 30, 0, 0, time.UTC)
+	assert.Equal(t, []model{
+		{Day: time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC), Closed: &closed},
+		{Day: time.Date(2023, 3, 3, 0, 0, 0, 0, time.UTC)},
+	}, items)
+}
+
+func TestScanTimeLayoutErrors(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("day", []driver.Value{"02/03/2023"}), nil
+	})
+
+	var m struct {
+		Day time.Time `sql:"day,layout=2006-01-02"`
+	}
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT day FROM test", nil, &m)
+	assert.Error(t, err)
+
+	var wrongType struct {
+		Day string `sql:"day,layout=2006-01-02"`
+	}
+	err = QueryRowIntoStruct(context.Background(), dbh, "SELECT day FROM test", nil, &wrongType)
+	assert.Error(t, err)
+}
+
+func TestScanJSONColumn(t *testing.T) {
+	type child struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	type parent struct {
+		ID       int64   `sql:"id"`
+		Children []child `sql:"children,json"`
+	}
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,children",
+			[]driver.Value{int64(1), []byte(`[{"id": 10, "name": "a"}, {"id": 11, "name": "b"}]`)},
+			[]driver.Value{int64(2), nil},
+		), nil
+	})
+
+	q := "SELECT p.id, json_agg(c.*) FILTER (WHERE c.id IS NOT NULL) AS children FROM parent p LEFT JOIN child c ON c.parent_id = p.id GROUP BY p.id"
+	items, err := QueryRowsIntoSlice(context.Background(), dbh, q, nil, parent{})
+	assert.NoError(t, err)
+	assert.Equal(t, []parent{
+		{ID: 1, Children: []child{{10, "a"}, {11, "b"}}},
+		{ID: 2},
+	}, items)
+}
+
+func TestScanNullString(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,name",
+			[]driver.Value{int64(1), "first"},
+			[]driver.Value{int64(2), nil},
+		), nil
+	})
+
+	items, err := QueryRowsIntoSlice(context.Background(), dbh, "SELECT id, name FROM test", nil, scanTestModel{}, NullString("—"))
+	assert.NoError(t, err)
+	assert.Equal(t, []scanTestModel{{1, "first"}, {2, "—"}}, items)
+
+	_, err = QueryRowsIntoSlice(context.Background(), dbh, "SELECT id, name FROM test", nil, scanTestModel{})
+	assert.Error(t, err)
+}
+
+type testCents int64
+
+func TestScanNamedNumericType(t *testing.T) {
+	type model struct {
+		ID      int64      `sql:"id"`
+		Balance testCents  `sql:"balance"`
+		Limit   *testCents `sql:"credit_limit"`
+	}
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,balance,credit_limit", []driver.Value{int64(1), int64(12345), int64(500)}), nil
+	})
+
+	var m model
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT id, balance, credit_limit FROM test", nil, &m)
+	assert.NoError(t, err)
+	limit := testCents(500)
+	assert.Equal(t, model{ID: 1, Balance: 12345, Limit: &limit}, m)
+}
+
+func TestScanIntegerOverflow(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("id,small", []driver.Value{int64(1), int64(1) << 31}), nil
+	})
+
+	var m struct {
+		ID    int   `sql:"id"`
+		Small int32 `sql:"small"`
+	}
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT id, small FROM test", nil, &m)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "out of range")
+	}
+
+	var small int8
+	err = QueryRowAndScan(context.Background(), dbh, "SELECT id, small FROM test", nil, new(int), &small)
+	assert.Error(t, err)
+}
+
+func TestScanNameMapper(t *testing.T) {
+	sqlstruct.NameMapper = sqlstruct.ToSnakeCase
+	defer func() { sqlstruct.NameMapper = strings.ToLower }()
+
+	type model struct {
+		CreatedAt string
+		FullName  string `sql:"FullName"`
+	}
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		return rowsOf("created_at,full_name", []driver.Value{"today", "first"}), nil
+	})
+
+	// like sqlstruct, the mapper is applied to tag names as well
+	var m model
+	err := QueryRowIntoStruct(context.Background(), dbh, "SELECT created_at, full_name FROM test", nil, &m, RequireAllFields())
+	assert.NoError(t, err)
+	assert.Equal(t, model{CreatedAt: "today", FullName: "first"}, m)
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+)
+
+// ColumnInfo describes a table column as reported by information_schema
+type ColumnInfo struct {
+	Name     string `sql:"column_name"`
+	DataType string `sql:"data_type"`
+	Nullable bool   `sql:"is_nullable"`
+	Ordinal  int    `sql:"ordinal_position"`
+}
+
+// TableColumns returns columns of schema.table in their ordinal order,
+// an unknown table has no columns
+func TableColumns(ctx context.Context, db Queryable, schema, table string) ([]ColumnInfo, error) {
+	q := `SELECT column_name, data_type, is_nullable = 'YES' AS is_nullable, ordinal_position
+		FROM information_schema.columns
+		WHERE table_schema = :schema AND table_name = :table
+		ORDER BY ordinal_position`
+	columns, err := QueryRowsIntoSlice(ctx, db, q, Params{"schema": schema, "table": table}, ColumnInfo{})
+	if err != nil {
+		return nil, err
+	}
+	return columns.([]ColumnInfo), nil
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableColumns(t *testing.T) {
+	dbh, _ := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		assert.True(t, strings.Contains(c.Query, "table_schema = 'public' AND table_name = 'users'"), c.Query)
+		return rowsOf("column_name,data_type,is_nullable,ordinal_position",
+			[]driver.Value{"id", "bigint", false, int64(1)},
+			[]driver.Value{"email", "text", true, int64(2)},
+		), nil
+	})
+
+	columns, err := TableColumns(context.Background(), dbh, "public", "users")
+	assert.NoError(t, err)
+	assert.Equal(t, []ColumnInfo{
+		{Name: "id", DataType: "bigint", Nullable: false, Ordinal: 1},
+		{Name: "email", DataType: "text", Nullable: true, Ordinal: 2},
+	}, columns)
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Shards routes queries to one of database handles by a key, e.g. tenant id,
+// which resolver takes from context
+type Shards struct {
+	resolve func(ctx context.Context) (string, bool)
+
+	mu       sync.RWMutex
+	handles  map[string]Queryable
+	fallback Queryable
+}
+
+// NewShards creates router which selects shards by the key resolve returns
+func NewShards(resolve func(ctx context.Context) (string, bool)) *Shards {
+	return &Shards{
+		resolve: resolve,
+		handles: make(map[string]Queryable),
+	}
+}
+
+// Register makes queries for key to go to db
+func (s *Shards) Register(key string, db Queryable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handles[key] = db
+}
+
+// SetDefault sets db used when key is not resolved or not registered
+func (s *Shards) SetDefault(db Queryable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = db
+}
+
+// For returns handle of the shard selected by ctx to be passed to the query
+// helpers. It fails when ctx has no key or no shard is registered for the
+// key, unless there is a default shard.
+func (s *Shards) For(ctx context.Context) (Queryable, error) {
+	key, ok := s.resolve(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ok {
+		if db, ok := s.handles[key]; ok {
+			return db, nil
+		}
+	}
+	if s.fallback != nil {
+		return s.fallback, nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("no shard key in context")
+	}
+	return nil, fmt.Errorf("shard %q is not registered", key)
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantContextKey struct{}
+
+func TestShards(t *testing.T) {
+	first, firstServer := newFakeDB(t, nil)
+	second, secondServer := newFakeDB(t, nil)
+
+	shards := NewShards(func(ctx context.Context) (string, bool) {
+		tenant, ok := ctx.Value(tenantContextKey{}).(string)
+		return tenant, ok
+	})
+	shards.Register("alpha", first)
+	shards.Register("beta", second)
+
+	ctx := context.WithValue(context.Background(), tenantContextKey{}, "alpha")
+	dbh, err := shards.For(ctx)
+	assert.NoError(t, err)
+	_, err = Exec(ctx, dbh, "DELETE FROM test WHERE id = :id", Params{"id": 1})
+	assert.NoError(t, err)
+
+	ctx = context.WithValue(context.Background(), tenantContextKey{}, "beta")
+	dbh, err = shards.For(ctx)
+	assert.NoError(t, err)
+	_, err = Exec(ctx, dbh, "DELETE FROM test WHERE id = :id", Params{"id": 2})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"DELETE FROM test WHERE id = 1"}, firstServer.Log())
+	assert.Equal(t, []string{"DELETE FROM test WHERE id = 2"}, secondServer.Log())
+
+	ctx = context.WithValue(context.Background(), tenantContextKey{}, "gamma")
+	_, err = shards.For(ctx)
+	assert.Error(t, err)
+	_, err = shards.For(context.Background())
+	assert.EqualError(t, err, "no shard key in context")
+
+	shards.SetDefault(first)
+	dbh, err = shards.For(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, first, dbh)
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+type txContextKey struct{}
+
+// txState is the transaction started by WithTx, carried in context.
+// db is the handle the transaction was begun on.
+type txState struct {
+	db         Queryable
+	tx         *sql.Tx
+	savepoints int
+}
+
+func txFromContext(ctx context.Context) *txState {
+	state, _ := ctx.Value(txContextKey{}).(*txState)
+	return state
+}
+
+// WithTx runs fn in a transaction which is committed when fn succeeds and
+// rolled back otherwise. When db is *sql.Tx or ctx carries a transaction
+// started on db by an outer WithTx, fn runs within a savepoint of that
+// transaction instead, so failed nested call rolls back only its own changes.
+// Transaction of another handle carried by ctx is not joined, a new one is
+// begun on db.
+//
+// The transaction is bound to ctx and fn gets ctx derived from it, so ctx
+// deadline is a budget shared by all queries of the transaction: once it's
+// spent the next query fails and the transaction is rolled back.
+func WithTx(ctx context.Context, db Queryable, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return withTx(ctx, db, nil, fn)
+}
+
+// WithReadTx is WithTx beginning a read-only transaction, so writes made
+// by fn fail. Called within a transaction it runs in a savepoint of that
+// transaction which, if it's not read-only, can't prevent writes.
+func WithReadTx(ctx context.Context, db Queryable, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return withTx(ctx, db, &sql.TxOptions{ReadOnly: true}, fn)
+}
+
+func withTx(ctx context.Context, db Queryable, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if tx, ok := db.(*sql.Tx); ok {
+		state := txFromContext(ctx)
+		if state == nil || state.tx != tx {
+			state = &txState{tx: tx}
+			ctx = context.WithValue(ctx, txContextKey{}, state)
+		}
+		return withSavepoint(ctx, state, fn)
+	}
+	if state := txFromContext(ctx); state != nil && state.db == db {
+		return withSavepoint(ctx, state, fn)
+	}
+
+	beginner, ok := db.(interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	})
+	if !ok {
+		return fmt.Errorf("%T can't begin transaction", db)
+	}
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	ctx = context.WithValue(ctx, txContextKey{}, &txState{db: db, tx: tx})
+
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+	if err = fn(ctx, tx); err != nil {
+		return err
+	}
+	committed = true
+	return tx.Commit()
+}
+
+func withSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	state.savepoints++
+	name := "db_sp_" + strconv.Itoa(state.savepoints)
+	if _, err = state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	released := false
+	defer func() {
+		if !released {
+			if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil && err != nil {
+				err = fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+			}
+		}
+	}()
+	if err = fn(ctx, state.tx); err != nil {
+		return err
+	}
+	released = true
+	_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// ExecNonTx runs statement which can't run inside a transaction block,
+// e.g. VACUUM or CREATE INDEX CONCURRENTLY, on a dedicated connection.
+// It doesn't join a transaction carried by ctx.
+func ExecNonTx(ctx context.Context, db *sql.DB, sql string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	logQuery(sql, nil)
+	_, err = conn.ExecContext(ctx, sql)
+	return err
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTx(t *testing.T) {
+	dbh, s := newFakeDB(t, nil)
+
+	err := WithTx(context.Background(), dbh, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := Exec(ctx, tx, "INSERT INTO test VALUES (:id)", Params{"id": 1})
+		return err
+	})
+	assert.NoError(t, err)
+
+	failure := errors.New("failure")
+	err = WithTx(context.Background(), dbh, func(ctx context.Context, tx *sql.Tx) error {
+		return failure
+	})
+	assert.Equal(t, failure, err)
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"INSERT INTO test VALUES (1)",
+		"COMMIT",
+		"BEGIN",
+		"ROLLBACK",
+	}, s.Log())
+}
+
+func TestWithTxNested(t *testing.T) {
+	dbh, s := newFakeDB(t, nil)
+
+	failure := errors.New("failure")
+	err := WithTx(context.Background(), dbh, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := Exec(ctx, tx, "INSERT INTO test VALUES (1)", nil); err != nil {
+			return err
+		}
+		// nested call gets the outer *sql.DB, the transaction is taken from context
+		err := WithTx(ctx, dbh, func(ctx context.Context, tx *sql.Tx) error {
+			if _, err := Exec(ctx, tx, "INSERT INTO test VALUES (2)", nil); err != nil {
+				return err
+			}
+			return failure
+		})
+		assert.Equal(t, failure, err)
+		// nested call gets the transaction itself
+		return WithTx(ctx, tx, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := Exec(ctx, tx, "INSERT INTO test VALUES (3)", nil)
+			return err
+		})
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"INSERT INTO test VALUES (1)",
+		"SAVEPOINT db_sp_1",
+		"INSERT INTO test VALUES (2)",
+		"ROLLBACK TO SAVEPOINT db_sp_1",
+		"SAVEPOINT db_sp_2",
+		"INSERT INTO test VALUES (3)",
+		"RELEASE SAVEPOINT db_sp_2",
+		"COMMIT",
+	}, s.Log())
+}
+
+func TestWithTxNestedOtherHandle(t *testing.T) {
+	first, firstServer := newFakeDB(t, nil)
+	other, otherServer := newFakeDB(t, nil)
+
+	err := WithTx(context.Background(), first, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := Exec(ctx, tx, "INSERT INTO test VALUES (1)", nil); err != nil {
+			return err
+		}
+		return WithTx(ctx, other, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := Exec(ctx, tx, "INSERT INTO other VALUES (1)", nil)
+			return err
+		})
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO test VALUES (1)", "COMMIT"}, firstServer.Log())
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO other VALUES (1)", "COMMIT"}, otherServer.Log())
+}
+
+func TestWithTxSharedDeadline(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		select {
+		case <-time.After(60 * time.Millisecond):
+			return nil, nil
+		case <-c.Ctx.Done():
+			return nil, c.Ctx.Err()
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var firstErr error
+	err := WithTx(ctx, dbh, func(ctx context.Context, tx *sql.Tx) error {
+		_, firstErr = Exec(ctx, tx, "UPDATE test SET a = 1", nil)
+		if firstErr != nil {
+			return firstErr
+		}
+		_, err := Exec(ctx, tx, "UPDATE test SET b = 2", nil)
+		return err
+	})
+	assert.NoError(t, firstErr)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+	assert.Equal(t, "BEGIN", s.Log()[0])
+	assert.NotContains(t, s.Log(), "COMMIT")
+}
+
+func TestExecNonTx(t *testing.T) {
+	var inTx []bool
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		inTx = append(inTx, c.InTx)
+		return nil, nil
+	})
+
+	err := WithTx(context.Background(), dbh, func(ctx context.Context, tx *sql.Tx) error {
+		return ExecNonTx(ctx, dbh, "VACUUM ANALYZE test")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "VACUUM ANALYZE test", "COMMIT"}, s.Log())
+	assert.Equal(t, []bool{false}, inTx)
+}
+
+func TestWithReadTx(t *testing.T) {
+	dbh, s := newFakeDB(t, func(c *fakeCall) (*fakeResult, error) {
+		if c.ReadOnly && strings.HasPrefix(c.Query, "UPDATE") {
+			return nil, &pgxError{Code: "25006", Message: "cannot execute UPDATE in a read-only transaction"}
+		}
+		return nil, nil
+	})
+
+	err := WithReadTx(context.Background(), dbh, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := Exec(ctx, tx, "SELECT 1", nil); err != nil {
+			return err
+		}
+		_, err := Exec(ctx, tx, "UPDATE test SET a = 1", nil)
+		return err
+	})
+	assert.Equal(t, "25006", sqlState(err))
+
+	assert.Equal(t, []string{
+		"BEGIN READ ONLY",
+		"SELECT 1",
+		"UPDATE test SET a = 1",
+		"ROLLBACK",
+	}, s.Log())
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateRenderedSQL makes Render and the query functions check rendered
+// SQL for unbalanced quotes and parens and leftover :name placeholders.
+// It's meant for tests and development, the check doesn't parse SQL.
+var ValidateRenderedSQL = false
+
+// Render substitutes params into q the same way Exec and Query do
+func Render(q string, params Params) (string, error) {
+	return qprintf(q, params)
+}
+
+// validateSQL is a lightweight tokenizer pass over rendered sql which
+// skips quoted literals, identifiers, comments and dollar-quoted strings
+func validateSQL(sql string) error {
+	depth, brackets := 0, 0
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'' || c == '"' || c == '-' || c == '/' || c == '$':
+			end := quotedEnd(sql, i)
+			if end == -1 {
+				return unterminatedError(sql, i)
+			}
+			if end > i {
+				i = end - 1
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced ) at offset %d", i)
+			}
+		case c == '[':
+			brackets++
+		case c == ']':
+			brackets--
+		case c == ParamPrefix && brackets <= 0:
+			// inside brackets it's array slice like a[1:2]
+			if i+1 < len(sql) && sql[i+1] == ParamPrefix {
+				i++
+				continue
+			}
+			if i+1 < len(sql) && isWordChar(sql[i+1]) {
+				return fmt.Errorf("leftover placeholder at offset %d", i)
+			}
+		}
+	}
+	if depth > 0 {
+		return fmt.Errorf("%d unclosed (", depth)
+	}
+	return nil
+}
+
+// quotedEnd returns index just past string literal, quoted identifier,
+// comment or dollar-quoted string starting at sql[i], i when none starts
+// there and -1 when it's not terminated
+func quotedEnd(sql string, i int) int {
+	switch c := sql[i]; {
+	case c == '\'' || c == '"':
+		end := closingQuote(sql, i+1, c, c == '\'' && isEscapeString(sql, i))
+		if end == -1 {
+			return -1
+		}
+		return end + 1
+	case strings.HasPrefix(sql[i:], "--"):
+		end := strings.IndexByte(sql[i:], '\n')
+		if end == -1 {
+			return len(sql)
+		}
+		return i + end + 1
+	case strings.HasPrefix(sql[i:], "/*"):
+		end := strings.Index(sql[i+2:], "*/")
+		if end == -1 {
+			return -1
+		}
+		return i + 2 + end + 2
+	// $ inside identifier like a$b doesn't start dollar quote
+	case c == '$' && (i == 0 || !isWordChar(sql[i-1])):
+		tag := dollarTag(sql[i:])
+		if tag == "" {
+			return i
+		}
+		end := strings.Index(sql[i+len(tag):], tag)
+		if end == -1 {
+			return -1
+		}
+		return i + len(tag) + end + len(tag)
+	}
+	return i
+}
+
+func unterminatedError(sql string, i int) error {
+	switch sql[i] {
+	case '/':
+		return fmt.Errorf("unterminated comment at offset %d", i)
+	case '$':
+		return fmt.Errorf("unterminated dollar quote %s at offset %d", dollarTag(sql[i:]), i)
+	}
+	return fmt.Errorf("unterminated quote %c at offset %d", sql[i], i)
+}
+
+// closingQuote returns index of the quote closing the one opened before
+// from, doubled quotes are escapes, so are backslashes when backslashEscapes
+// is set, i.e. in E-prefixed literals
+func closingQuote(sql string, from int, quote byte, backslashEscapes bool) int {
+	for i := from; i < len(sql); i++ {
+		if backslashEscapes && sql[i] == '\\' {
+			i++
+			continue
+		}
+		if sql[i] != quote {
+			continue
+		}
+		if i+1 < len(sql) && sql[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// isEscapeString reports whether the quote at sql[i] opens E'...' literal
+func isEscapeString(sql string, i int) bool {
+	return i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i == 1 || !isWordChar(sql[i-2]))
+}
+
+// dollarTag returns $tag$ opening a dollar-quoted string at the start of s
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1]
+		}
+		if !isWordChar(s[i]) || (i == 1 && s[i] >= '0' && s[i] <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isWordChar(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || c == '_' || (c >= '0' && c <= '9')
+}
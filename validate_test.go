@@ -0,0 +1,59 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSQL(t *testing.T) {
+	cases := []struct {
+		sql   string
+		valid bool
+	}{
+		{"SELECT * FROM t WHERE id = 1", true},
+		{"SELECT 'it''s', \"col\"\"umn\" FROM t", true},
+		{"SELECT '(:x' FROM t", true},
+		{"SELECT (1 + 2)::text -- :comment\nFROM t", true},
+		{"SELECT /* ( */ $$ ' $$, $f$:x$f$, $1", true},
+		{"SELECT 'abc FROM t", false},
+		{"SELECT \"abc FROM t", false},
+		{"SELECT (1 + 2 FROM t", false},
+		{"SELECT 1 + 2) FROM t", false},
+		{"SELECT * FROM t WHERE id = :id", false},
+		{"SELECT $$abc", false},
+		{"SELECT a[1:2], a[:3], a[2:] FROM t", true},
+		{"SELECT a[1:2] FROM t WHERE id = :id", false},
+		{`SELECT E'\'', E'\\', 'a\' FROM t`, true},
+		{`SELECT E'\' FROM t`, false},
+		{`SELECT CASE WHEN a THEN 1 ELSE'\' END`, true},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d", i+1), func(t *testing.T) {
+			err := validateSQL(c.sql)
+			if c.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRenderValidated(t *testing.T) {
+	ValidateRenderedSQL = true
+	defer func() { ValidateRenderedSQL = false }()
+
+	sql, err := Render("SELECT * FROM t WHERE id = :id AND name = :name", Params{"id": 1, "name": "a'b:c"})
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE id = 1 AND name = 'a''b:c'", sql)
+
+	raw := func(v interface{}) (string, error) { return v.(string), nil }
+
+	_, err = Render("SELECT * FROM t WHERE name = :name", Params{"name": RenderAs("'abc", raw)})
+	assert.Error(t, err)
+
+	_, err = Render("SELECT * FROM t WHERE id = :id", Params{"id": RenderAs(":other", raw)})
+	assert.Error(t, err)
+}